@@ -1,6 +1,8 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/containernetworking/cni/pkg/skel"
@@ -11,6 +13,11 @@ import (
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/plugin"
 )
 
+const (
+	reconcileCmd  = "reconcile"
+	vlanLedgerCmd = "vlan-ledger"
+)
+
 func setupLogger() {
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	log.Logger = log.Output(zerolog.ConsoleWriter{
@@ -20,9 +27,75 @@ func setupLogger() {
 	})
 }
 
+// runReconcile releases cached attachments which network namespace no longer exists
+func runReconcile(p *plugin.Plugin, args []string) int {
+	fs := flag.NewFlagSet(reconcileCmd, flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "enable debug logging")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n", os.Args[0], reconcileCmd)
+		fmt.Fprintln(fs.Output(), "Release VFs and representors of attachments which network namespace no longer exists")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+	if err := p.Reconcile(); err != nil {
+		log.Error().Msgf("reconcile failed - %v.", err)
+		return 1
+	}
+	return 0
+}
+
+// runVlanLedger dumps or repairs the uplink VLAN ledger
+func runVlanLedger(p *plugin.Plugin, args []string) int {
+	fs := flag.NewFlagSet(vlanLedgerCmd, flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "enable debug logging")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options] dump|repair\n", os.Args[0], vlanLedgerCmd)
+		fmt.Fprintln(fs.Output(), "Dump the uplink VLAN ledger or remove VLANs of attachments which are no longer cached")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+	var err error
+	switch fs.Arg(0) {
+	case "dump":
+		err = p.DumpVlanLedger(os.Stdout)
+	case "repair":
+		err = p.RepairVlanLedger()
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		log.Error().Msgf("%s %s failed - %v.", vlanLedgerCmd, fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
 func main() {
 	setupLogger()
 	p := plugin.NewPlugin()
-	skel.PluginMain(p.CmdAdd, p.CmdCheck, p.CmdDel,
-		version.PluginSupports("0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0"), "")
+	// CNI runtime doesn't pass command line arguments to the plugin
+	if len(os.Args) > 1 && os.Args[1] == reconcileCmd {
+		os.Exit(runReconcile(p, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == vlanLedgerCmd {
+		os.Exit(runVlanLedger(p, os.Args[2:]))
+	}
+	skel.PluginMainFuncs(skel.CNIFuncs{
+		Add:    p.CmdAdd,
+		Del:    p.CmdDel,
+		Check:  p.CmdCheck,
+		GC:     p.CmdGC,
+		Status: p.CmdStatus,
+	}, version.All, "")
 }
@@ -3,10 +3,18 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 )
 
+const (
+	// stateRefSeparator joins network, container ID and interface name in the state reference
+	stateRefSeparator = "-"
+	// stateRefMinSeparators is a minimal number of separators in the state reference
+	stateRefMinSeparators = 2
+)
+
 var (
 	// CacheDir is used By default for caching CNI network state
 	CacheDir = "/var/lib/cni/accelerated-bridge"
@@ -23,6 +31,9 @@ type StateCache interface {
 	Load(ref StateRef, state interface{}) error
 	// Delete state from cache
 	Delete(ref StateRef) error
+	// List State references for the network, all references are returned if network is empty.
+	// Files which don't follow the state reference naming, like lock files, are skipped
+	List(network string) ([]StateRef, error)
 }
 
 // Create a new state Cache that will Save/Load state
@@ -36,7 +47,7 @@ type FsStateCache struct {
 }
 
 func (sc *FsStateCache) GetStateRef(network, cid, ifname string) StateRef {
-	return StateRef(strings.Join([]string{network, cid, ifname}, "-"))
+	return StateRef(strings.Join([]string{network, cid, ifname}, stateRefSeparator))
 }
 
 func (sc *FsStateCache) Save(ref StateRef, state interface{}) error {
@@ -78,3 +89,39 @@ func (sc *FsStateCache) Delete(ref StateRef) error {
 	}
 	return nil
 }
+
+func (sc *FsStateCache) List(network string) ([]StateRef, error) {
+	infos, err := sc.fsOps.ReadDir(sc.basePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory(%q): %v", sc.basePath, err)
+	}
+	prefix := ""
+	if network != "" {
+		prefix = network + stateRefSeparator
+	}
+	var refs []StateRef
+	for _, info := range infos {
+		if info.IsDir() || !IsStateRef(info.Name()) || !strings.HasPrefix(info.Name(), prefix) {
+			continue
+		}
+		refs = append(refs, StateRef(info.Name()))
+	}
+	return refs, nil
+}
+
+// IsStateRef returns true if the file name follows <network>-<cid>-<ifname> naming of the state reference
+func IsStateRef(name string) bool {
+	parts := strings.Split(name, stateRefSeparator)
+	if len(parts) <= stateRefMinSeparators {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+	}
+	return !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, ".lock")
+}
@@ -80,4 +80,45 @@ var _ = Describe("Cache - Simple marshall-able state-object cache", func() {
 			})
 		})
 	})
+
+	Describe("List State", func() {
+		Context("Cache directory doesn't exist", func() {
+			It("Should return empty list", func() {
+				refs, err := stateCache.List("mynet")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(BeEmpty())
+			})
+		})
+		Context("Saved states for multiple networks", func() {
+			It("Should list states of the network", func() {
+				savedState := myTestState{FirstState: "first", SecondState: 42}
+				mynetRef := stateCache.GetStateRef("mynet", "cid", "net1")
+				altRef := stateCache.GetStateRef("alt", "cid", "net2")
+				Expect(stateCache.Save(mynetRef, &savedState)).Should(Succeed())
+				Expect(stateCache.Save(altRef, &savedState)).Should(Succeed())
+				refs, err := stateCache.List("mynet")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(mynetRef))
+				refs, err = stateCache.List("")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(mynetRef, altRef))
+			})
+		})
+		Context("Stray files in cache directory", func() {
+			It("Should skip files which are not state references", func() {
+				savedState := myTestState{FirstState: "first", SecondState: 42}
+				mynetRef := stateCache.GetStateRef("mynet", "cid", "net1")
+				Expect(stateCache.Save(mynetRef, &savedState)).Should(Succeed())
+				for _, name := range []string{"vlan-uplink.lock", "mynet-cid-net2.lock", "mynet--net1", ".mynet-cid-net1"} {
+					Expect(fs.WriteFile(path.Join(CacheDir, name), []byte("{}"), 0600)).Should(Succeed())
+				}
+				refs, err := stateCache.List("")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(mynetRef))
+				refs, err = stateCache.List("vlan")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(BeEmpty())
+			})
+		})
+	})
 })
@@ -40,6 +40,29 @@ func (_m *StateCache) GetStateRef(network string, cid string, ifname string) cac
 	return r0
 }
 
+// List provides a mock function with given fields: network
+func (_m *StateCache) List(network string) ([]cache.StateRef, error) {
+	ret := _m.Called(network)
+
+	var r0 []cache.StateRef
+	if rf, ok := ret.Get(0).(func(string) []cache.StateRef); ok {
+		r0 = rf(network)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cache.StateRef)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(network)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Load provides a mock function with given fields: ref, state
 func (_m *StateCache) Load(ref cache.StateRef, state interface{}) error {
 	ret := _m.Called(ref, state)
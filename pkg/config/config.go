@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 
+	"github.com/containernetworking/cni/pkg/version"
+
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
 )
@@ -46,6 +49,11 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		return err
 	}
 
+	// Parse previous result, set when plugin is used in a plugin chain
+	if err := version.ParsePrevResult(&conf.NetConf.NetConf); err != nil {
+		return fmt.Errorf("failed to parse prevResult: %v", err)
+	}
+
 	conf.MAC = conf.NetConf.MAC
 	conf.MTU = conf.NetConf.MTU
 
@@ -54,11 +62,10 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		return fmt.Errorf("VF pci addr is required")
 	}
 
-	// Get rest of the VF information
-	var err error
-	conf.PFName, conf.VFID, err = c.getVfInfo(conf.DeviceID)
+	// Get rest of the VF or SF information
+	err := c.handleDeviceConfig(conf)
 	if err != nil {
-		return fmt.Errorf("failed to get VF information: %q", err)
+		return err
 	}
 
 	err = c.handleBridgeConfig(conf)
@@ -66,20 +73,6 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		return err
 	}
 
-	// Assuming VF is netdev interface; Get interface name
-	hostIFName, err := utils.GetVFLinkName(conf.DeviceID)
-	if err != nil || hostIFName == "" {
-		conf.IsUserspaceDriver, err = utils.HasUserspaceDriver(conf.DeviceID)
-		if err != nil {
-			return fmt.Errorf("failed to detect if VF %s has userspace driver %q", conf.DeviceID, err)
-		}
-		if !conf.IsUserspaceDriver {
-			return fmt.Errorf("the VF %s does not have a interface name or a userspace driver", conf.DeviceID)
-		}
-	}
-
-	conf.OrigVfState.HostIFName = hostIFName
-
 	// validate vlan id range
 	if conf.Vlan < 0 || conf.Vlan > 4094 {
 		return fmt.Errorf("vlan id %d invalid: value must be in the range 0-4094", conf.Vlan)
@@ -93,9 +86,340 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		}
 	}
 
+	if conf.VlanProtocol != "" {
+		if err = handleVlanProtocol(conf); err != nil {
+			return err
+		}
+	}
+
+	if conf.VxlanDevice != "" || len(conf.VniMap) > 0 {
+		if err = validateVniMap(conf); err != nil {
+			return err
+		}
+	}
+
+	if err = validateVfSettings(conf); err != nil {
+		return err
+	}
+
+	if err = validateMACConfig(conf); err != nil {
+		return err
+	}
+
+	if err = validateSysctl(conf); err != nil {
+		return err
+	}
+
+	if conf.AnnounceCount < 0 || conf.AnnounceInterval < 0 || conf.DADTimeout < 0 || conf.CarrierTimeout < 0 {
+		return fmt.Errorf("announceCount, announceInterval, dadTimeout and carrierTimeout can't be negative")
+	}
+
+	// learning is disabled on representor port when static FDB is used
+	if conf.StaticFdb && conf.PortFlags != nil && conf.PortFlags.Learning != nil && *conf.PortFlags.Learning {
+		return fmt.Errorf("learning port flag can't be enabled with staticFdb option")
+	}
+
+	// validate bandwidth limits
+	if conf.RuntimeConfig.Bandwidth != nil {
+		if err = validateBandwidth(conf.RuntimeConfig.Bandwidth); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// handleVlanProtocol validates VLAN protocol configuration.
+// In 802.1ad mode vlan option is a service VLAN (S-tag) which is set as PVID for representor,
+// all frames from VF are carried inside the S-tag. Trunk VLANs are customer VLANs (C-tags) which are
+// allowed inside the S-tag, they are not configured on the bridge
+func handleVlanProtocol(conf *localtypes.PluginConf) error {
+	if _, err := utils.GetVlanProtocolID(conf.VlanProtocol); err != nil {
+		return err
+	}
+	if conf.CreateBridge && conf.BridgeVlanProtocol != "" && conf.BridgeVlanProtocol != conf.VlanProtocol {
+		return fmt.Errorf("bridgeVlanProtocol %s doesn't match vlanProtocol %s",
+			conf.BridgeVlanProtocol, conf.VlanProtocol)
+	}
+	if conf.VlanProtocol != utils.VlanProtocol8021AD {
+		return nil
+	}
+	if conf.Vlan == 0 {
+		return fmt.Errorf("vlan option is required for %s VLAN protocol", conf.VlanProtocol)
+	}
+	conf.CTags, conf.Trunk = conf.Trunk, nil
+	return nil
+}
+
+// validateVniMap checks that VXLAN device is set together with VLAN to VNI mappings
+// and that each VLAN configured for the VF is mapped to a unique VNI
+func validateVniMap(conf *localtypes.PluginConf) error {
+	if conf.VxlanDevice == "" {
+		return fmt.Errorf("vniMap option requires vxlanDevice option")
+	}
+	if len(conf.VniMap) == 0 {
+		return fmt.Errorf("vxlanDevice option requires vniMap option")
+	}
+	vlans := make(map[int]bool, len(conf.Trunk)+1)
+	for _, vlan := range conf.Trunk {
+		vlans[vlan] = true
+	}
+	if conf.Vlan > 0 {
+		vlans[conf.Vlan] = true
+	}
+	mappedVlans := make(map[int]bool, len(conf.VniMap))
+	mappedVnis := make(map[uint32]bool, len(conf.VniMap))
+	for _, entry := range conf.VniMap {
+		if !vlans[entry.Vlan] {
+			return fmt.Errorf("vniMap VLAN %d is not configured with vlan or trunk options", entry.Vlan)
+		}
+		if entry.Vni == 0 || entry.Vni > utils.MaxVni {
+			return fmt.Errorf("vni %d invalid: value must be in the range 1-%d", entry.Vni, utils.MaxVni)
+		}
+		if mappedVlans[entry.Vlan] || mappedVnis[entry.Vni] {
+			return fmt.Errorf("vniMap contains duplicate mapping for VLAN %d or VNI %d", entry.Vlan, entry.Vni)
+		}
+		mappedVlans[entry.Vlan] = true
+		mappedVnis[entry.Vni] = true
+	}
+	if len(mappedVlans) != len(vlans) {
+		return fmt.Errorf("vniMap should contain mappings for all VLANs from vlan and trunk options")
+	}
+	return nil
+}
+
+// validateVfSettings checks VF administrative settings which are applied through the PF
+func validateVfSettings(conf *localtypes.PluginConf) error {
+	if err := validateOnOff("spoofchk", conf.SpoofChk); err != nil {
+		return err
+	}
+	if err := validateOnOff("trust", conf.Trust); err != nil {
+		return err
+	}
+	if conf.LinkState != "" {
+		if _, err := utils.GetVfLinkState(conf.LinkState); err != nil {
+			return err
+		}
+	}
+	if conf.BindDriver != "" && conf.BindDriver != utils.DriverVfioPci {
+		return fmt.Errorf("bindDriver %q invalid: only %s driver is supported", conf.BindDriver, utils.DriverVfioPci)
+	}
+	if conf.IsSF {
+		return validateSfSettings(conf)
+	}
+	switch conf.MACStrategy {
+	case "", utils.MACStrategyAuto, utils.MACStrategyDevlink, utils.MACStrategyLegacy:
+	default:
+		return fmt.Errorf("macStrategy %q invalid: supported values are %s, %s, %s",
+			conf.MACStrategy, utils.MACStrategyAuto, utils.MACStrategyDevlink, utils.MACStrategyLegacy)
+	}
+	if conf.MinTxRate != nil && *conf.MinTxRate < 0 {
+		return fmt.Errorf("min_tx_rate %d invalid: value must be positive", *conf.MinTxRate)
+	}
+	if conf.MaxTxRate != nil && *conf.MaxTxRate < 0 {
+		return fmt.Errorf("max_tx_rate %d invalid: value must be positive", *conf.MaxTxRate)
+	}
+	// max_tx_rate 0 means no limit
+	if conf.MinTxRate != nil && conf.MaxTxRate != nil && *conf.MaxTxRate != 0 && *conf.MinTxRate > *conf.MaxTxRate {
+		return fmt.Errorf("min_tx_rate %d can't be greater than max_tx_rate %d", *conf.MinTxRate, *conf.MaxTxRate)
+	}
+	return nil
+}
+
+// validateMACConfig checks MAC options, MAC from CNI_ARGS is checked when the MAC is chosen during ADD
+func validateMACConfig(conf *localtypes.PluginConf) error {
+	for _, mac := range []string{conf.MAC, conf.RuntimeConfig.Mac} {
+		if mac == "" {
+			continue
+		}
+		if err := utils.ValidateMAC(mac); err != nil {
+			return err
+		}
+	}
+	if conf.MACPrefix != "" {
+		if _, err := utils.ParseMACPrefix(conf.MACPrefix); err != nil {
+			return err
+		}
+	}
+	switch conf.MACConflictPolicy {
+	case "", utils.MACConflictPolicyWarn, utils.MACConflictPolicyFail, utils.MACConflictPolicyIgnore:
+	default:
+		return fmt.Errorf("macConflictPolicy %q invalid: supported values are %s, %s, %s", conf.MACConflictPolicy,
+			utils.MACConflictPolicyWarn, utils.MACConflictPolicyFail, utils.MACConflictPolicyIgnore)
+	}
+	return nil
+}
+
+// validateSysctl checks that sysctl keys belong to the pod interface,
+// the interface name in the keys is checked when the sysctls are applied during ADD
+func validateSysctl(conf *localtypes.PluginConf) error {
+	if len(conf.Sysctl) == 0 {
+		return nil
+	}
+	if conf.IsUserspaceDriver || conf.BindDriver != "" {
+		return fmt.Errorf("sysctl option is not supported for VF with userspace driver")
+	}
+	for key := range conf.Sysctl {
+		if _, _, _, err := utils.ParseInterfaceSysctl(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSfSettings checks that only settings supported for SF are configured,
+// VF administrative settings are not available for SF and SF MAC can be set only through devlink
+func validateSfSettings(conf *localtypes.PluginConf) error {
+	if conf.SpoofChk != "" || conf.Trust != "" || conf.LinkState != "" ||
+		conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		return fmt.Errorf("spoofchk, trust, link_state, min_tx_rate and max_tx_rate options are not supported for SF")
+	}
+	if conf.BindDriver != "" {
+		return fmt.Errorf("bindDriver option is not supported for SF")
+	}
+	switch conf.MACStrategy {
+	case "", utils.MACStrategyAuto, utils.MACStrategyDevlink:
+	default:
+		return fmt.Errorf("macStrategy %q invalid: SF MAC can be set only with %s strategy",
+			conf.MACStrategy, utils.MACStrategyDevlink)
+	}
+	return nil
+}
+
+func validateOnOff(option, value string) error {
+	if value != "" && value != "on" && value != "off" {
+		return fmt.Errorf("%s option %q invalid: value must be on or off", option, value)
+	}
+	return nil
+}
+
+// validateBandwidth checks that burst is set for each configured rate
+// and that rate and burst fit into the tc police action
+func validateBandwidth(bw *localtypes.BandwidthEntry) error {
+	if err := validateRateAndBurst("ingress", bw.IngressRate, bw.IngressBurst); err != nil {
+		return err
+	}
+	return validateRateAndBurst("egress", bw.EgressRate, bw.EgressBurst)
+}
+
+func validateRateAndBurst(direction string, rate, burst uint64) error {
+	if rate == 0 {
+		return nil
+	}
+	if burst == 0 {
+		return fmt.Errorf("%s burst must be set when %s rate is set", direction, direction)
+	}
+	// police action accepts rate and burst in bytes as 32-bit values
+	if rate/8 > math.MaxUint32 {
+		return fmt.Errorf("%s rate %d is too big", direction, rate)
+	}
+	if burst/8 > math.MaxUint32 {
+		return fmt.Errorf("%s burst %d is too big", direction, burst)
+	}
+	return nil
+}
+
+// handleDeviceConfig resolves PF, function index and netdevice name of the device from DeviceID option,
+// DeviceID is a PCI address of a VF or a name of SF auxiliary device, e.g. mlx5_core.sf.2
+func (c *Config) handleDeviceConfig(conf *localtypes.PluginConf) error {
+	var err error
+	if utils.IsAuxDevice(conf.DeviceID) {
+		conf.IsSF = true
+		conf.PFName, conf.SFNum, err = c.getSfInfo(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to get SF information: %q", err)
+		}
+		conf.OrigVfState.HostIFName, err = c.getSfLinkName(conf.DeviceID)
+		return err
+	}
+
+	conf.PFName, conf.VFID, err = c.getVfInfo(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to get VF information: %q", err)
+	}
+
+	vdpaDev, err := utils.GetVdpaDevice(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to detect vDPA device of VF %s: %v", conf.DeviceID, err)
+	}
+	if vdpaDev != nil {
+		if conf.BindDriver != "" {
+			return fmt.Errorf("bindDriver option can't be used for VF %s with vDPA device %s",
+				conf.DeviceID, vdpaDev.Name)
+		}
+		handleVdpaDevice(conf, vdpaDev)
+		return nil
+	}
+
+	// Assuming VF is netdev interface; Get interface name
+	// VF is bound to the userspace driver from bindDriver option by the manager during ADD
+	hostIFName, err := utils.GetVFLinkName(conf.DeviceID)
+	if (err != nil || hostIFName == "") && conf.BindDriver == "" {
+		if err = handleUserspaceDriver(conf); err != nil {
+			return err
+		}
+	}
+
+	conf.OrigVfState.HostIFName = hostIFName
+	return nil
+}
+
+// handleUserspaceDriver checks that the VF without netdevice is bound to one of the userspace drivers
+// and sets the driver information, default UserspaceDrivers list is used if the list is not configured
+func handleUserspaceDriver(conf *localtypes.PluginConf) error {
+	drivers := conf.UserspaceDrivers
+	if len(drivers) == 0 {
+		drivers = utils.UserspaceDrivers
+	}
+	driver, err := utils.GetUserspaceDriver(conf.DeviceID, drivers)
+	if err != nil {
+		return fmt.Errorf("failed to detect if VF %s has userspace driver %q", conf.DeviceID, err)
+	}
+	if driver == "" {
+		return fmt.Errorf("the VF %s does not have a interface name or a userspace driver (%s)",
+			conf.DeviceID, strings.Join(drivers, ", "))
+	}
+	conf.IsUserspaceDriver = true
+	conf.UserspaceDriver = driver
+	conf.UserspaceDevice, err = utils.GetUserspaceDevicePath(conf.DeviceID, driver)
+	return err
+}
+
+// handleVdpaDevice sets vDPA device information for the VF,
+// vhost-vdpa device is handled as VF with userspace driver, virtio netdevice is used instead of VF netdevice
+func handleVdpaDevice(conf *localtypes.PluginConf, vdpaDev *utils.VdpaDevice) {
+	conf.VdpaDevice = vdpaDev.Name
+	conf.VdpaDriver = vdpaDev.Driver
+	if vdpaDev.Driver == utils.VdpaDriverVhost {
+		conf.VdpaPath = vdpaDev.Path
+		conf.IsUserspaceDriver = true
+		return
+	}
+	conf.OrigVfState.HostIFName = vdpaDev.NetDev
+}
+
+func (c *Config) getSfInfo(auxDev string) (string, int, error) {
+	pf, err := c.sriovnetProvider.GetUplinkRepresentorFromAux(auxDev)
+	if err != nil {
+		return "", 0, err
+	}
+
+	sfNum, err := c.sriovnetProvider.GetSfIndexByAuxDev(auxDev)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return pf, sfNum, nil
+}
+
+func (c *Config) getSfLinkName(auxDev string) (string, error) {
+	names, err := c.sriovnetProvider.GetNetDevicesFromAux(auxDev)
+	if err != nil || len(names) == 0 {
+		return "", fmt.Errorf("the SF %s does not have a interface name: %v", auxDev, err)
+	}
+	return names[0], nil
+}
+
 func (c *Config) getVfInfo(vfPci string) (string, int, error) {
 	var vfID int
 
@@ -112,6 +436,24 @@ func (c *Config) getVfInfo(vfPci string) (string, int, error) {
 	return pf, vfID, nil
 }
 
+// GetBridgeNames returns list of bridge names from the bridge configuration option.
+// DefaultBridge is returned if the option is empty.
+func GetBridgeNames(bridge string) ([]string, error) {
+	if bridge == "" {
+		return []string{DefaultBridge}, nil
+	}
+	bridgeNamesInConf := strings.Split(bridge, ",")
+	bridgeNames := make([]string, 0, len(bridgeNamesInConf))
+	for _, brName := range bridgeNamesInConf {
+		brName = strings.TrimSpace(brName)
+		if brName == "" {
+			return nil, fmt.Errorf("bridge configuration option has invalid format")
+		}
+		bridgeNames = append(bridgeNames, brName)
+	}
+	return bridgeNames, nil
+}
+
 // handleBridgeConfig checks CNI bridge configuration and set ActualBridge options for PluginConfig.
 // If config.Bridge option is empty, config.ActualBridge will be the value of DefaultBridge const.
 // If config.Bridge option contains one bridge name, config.ActualBridge will be that bridge.
@@ -125,17 +467,23 @@ func (c *Config) handleBridgeConfig(conf *localtypes.PluginConf) error {
 	if conf.Bridge == "" {
 		conf.Bridge = DefaultBridge
 	}
-	bridgeNamesInConf := strings.Split(conf.Bridge, ",")
-	allowedBridgeNames := make([]string, 0, len(bridgeNamesInConf))
-	for _, brName := range bridgeNamesInConf {
-		brName = strings.TrimSpace(brName)
-		if brName == "" {
-			return fmt.Errorf("bridge configuration option has invalid format")
-		}
-		allowedBridgeNames = append(allowedBridgeNames, brName)
+	allowedBridgeNames, err := GetBridgeNames(conf.Bridge)
+	if err != nil {
+		return err
 	}
-	if len(allowedBridgeNames) == 0 {
-		return fmt.Errorf("bridge configuration option has invalid format")
+
+	if conf.CreateBridge {
+		if len(allowedBridgeNames) != 1 {
+			return fmt.Errorf("createBridge option requires a single bridge in bridge option")
+		}
+		if conf.BridgeVlanProtocol != "" {
+			if _, err = utils.GetVlanProtocolID(conf.BridgeVlanProtocol); err != nil {
+				return err
+			}
+		}
+		if conf.BridgeMTU < 0 {
+			return fmt.Errorf("bridgeMTU %d invalid: value must be positive", conf.BridgeMTU)
+		}
 	}
 
 	if len(allowedBridgeNames) == 1 {
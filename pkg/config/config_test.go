@@ -57,6 +57,20 @@ var _ = Describe("Config", func() {
 				err := conf.ParseConf(data, pluginConf)
 				Expect(err).To(HaveOccurred())
 			})
+			It("Invalid configuration - prevResult version mismatch", func() {
+				data := []byte(`{
+					"cniVersion": "0.3.1",
+					"name": "mynet",
+					"type": "accelerated-bridge",
+					"deviceID": "0000:af:06.1",
+					"prevResult": {
+						"cniVersion": "1.0.0",
+						"interfaces": [{"name": "eth0", "sandbox": "/proc/1/ns/net"}]
+					}
+				}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).To(HaveOccurred())
+			})
 		})
 		When("DeviceID exist", func() {
 			BeforeEach(func() {
@@ -86,6 +100,246 @@ var _ = Describe("Config", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 			})
+			Context("vDPA checks", func() {
+				It("Valid configuration - VF with vhost-vdpa device", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"vlan": 100
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.VFID).To(Equal(2))
+					Expect(pluginConf.IsUserspaceDriver).To(BeTrue())
+					Expect(pluginConf.VdpaDevice).To(Equal("vdpa0"))
+					Expect(pluginConf.VdpaDriver).To(Equal("vhost_vdpa"))
+					Expect(pluginConf.VdpaPath).To(Equal("/dev/vhost-vdpa-0"))
+				})
+				It("Valid configuration - VF without vDPA device", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.VdpaDevice).To(BeEmpty())
+					Expect(pluginConf.OrigVfState.HostIFName).To(Equal("enp175s7"))
+				})
+			})
+			Context("MAC generation checks", func() {
+				It("Valid configuration - MAC prefix", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"macGenerate": true,
+						"macPrefix": "0a:58:0a"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.MACGenerate).To(BeTrue())
+				})
+				It("Invalid configuration - MAC prefix is not locally administered", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"macGenerate": true,
+						"macPrefix": "00:11:22"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("MAC checks", func() {
+				It("Invalid configuration - multicast MAC", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"mac": "01:00:5e:00:00:01"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - all-zero MAC in runtime config", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"runtimeConfig": {"mac": "00:00:00:00:00:00"}
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Valid configuration - MAC conflict policy", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"mac": "b2:ec:90:4c:5b:11",
+						"macConflictPolicy": "fail"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.MACConflictPolicy).To(Equal("fail"))
+				})
+				It("Invalid configuration - unknown MAC conflict policy", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"macConflictPolicy": "abort"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Address announcement checks", func() {
+				It("Valid configuration - announcements with DAD", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"announceCount": 3,
+						"announceInterval": 200,
+						"waitForDAD": true,
+						"dadTimeout": 5
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.AnnounceCount).To(Equal(3))
+					Expect(pluginConf.WaitForDAD).To(BeTrue())
+				})
+				It("Invalid configuration - negative announce count", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"announceCount": -1
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Carrier checks", func() {
+				It("Valid configuration - wait for carrier", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"waitForCarrier": true,
+						"carrierTimeout": 5
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.WaitForCarrier).To(BeTrue())
+					Expect(pluginConf.CarrierTimeout).To(Equal(5))
+				})
+				It("Invalid configuration - negative carrier timeout", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"waitForCarrier": true,
+						"carrierTimeout": -1
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Sysctl checks", func() {
+				It("Valid configuration - per-interface sysctls", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {
+							"net.ipv6.conf.IFNAME.accept_ra": "0",
+							"net.ipv4.conf.IFNAME.arp_notify": "1"
+						}
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.Sysctl).To(HaveKeyWithValue("net.ipv6.conf.IFNAME.accept_ra", "0"))
+				})
+				It("Invalid configuration - global sysctl", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {"net.ipv4.conf.all.rp_filter": "0"}
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - sysctl with bindDriver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"bindDriver": "vfio-pci",
+						"sysctl": {"net.ipv6.conf.IFNAME.accept_ra": "0"}
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Driver bind checks", func() {
+				It("Valid configuration - bind VF to vfio-pci", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"bindDriver": "vfio-pci"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.BindDriver).To(Equal("vfio-pci"))
+					Expect(pluginConf.OrigVfState.HostIFName).To(Equal("enp175s7"))
+				})
+				It("Invalid configuration - unsupported driver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"bindDriver": "igb_uio"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VF with vDPA device", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"bindDriver": "vfio-pci"
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Chained plugin checks", func() {
+				It("Valid configuration - prevResult", func() {
+					data := []byte(`{
+						"cniVersion": "1.0.0",
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"prevResult": {
+							"cniVersion": "1.0.0",
+							"interfaces": [{"name": "eth0", "sandbox": "/proc/1/ns/net"}],
+							"ips": [{"address": "10.0.0.2/24", "interface": 0}]
+						}
+					}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.PrevResult).NotTo(BeNil())
+					Expect(pluginConf.RawPrevResult).To(BeNil())
+				})
+			})
 			Context("VLAN config checks", func() {
 				It("Valid configuration - complex trunk config", func() {
 					data := []byte(`{
@@ -151,6 +405,259 @@ var _ = Describe("Config", func() {
 					Expect(err).To(HaveOccurred())
 				})
 			})
+			Context("VLAN protocol config checks", func() {
+				It("Valid configuration - 802.1ad service VLAN", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlanProtocol": "802.1ad",
+							"vlan": 100
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.Vlan).To(Equal(100))
+				})
+				It("Valid configuration - 802.1ad with trunk C-tags", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlanProtocol": "802.1ad",
+							"vlan": 100,
+							"trunk": [{ "minID": 10, "maxID": 12 }]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.Vlan).To(Equal(100))
+					// C-tags are not configured on the bridge
+					Expect(pluginConf.Trunk).To(BeEmpty())
+					Expect(pluginConf.CTags).To(Equal([]int{10, 11, 12}))
+				})
+				It("Invalid configuration - 802.1ad with VNI mapping of C-tag", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlanProtocol": "802.1ad",
+							"vlan": 100,
+							"trunk": [{ "id": 10 }],
+							"vxlanDevice": "vxlan0",
+							"vniMap": [{"vlan": 100, "vni": 10100}, {"vlan": 10, "vni": 10010}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - 802.1ad without vlan", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlanProtocol": "802.1ad"
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - unknown VLAN protocol", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlanProtocol": "qinq",
+							"vlan": 100
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - bridgeVlanProtocol doesn't match vlanProtocol", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"createBridge": true,
+							"bridgeVlanProtocol": "802.1Q",
+							"vlanProtocol": "802.1ad",
+							"vlan": 100
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("VXLAN config checks", func() {
+				It("Valid configuration - all VLANs are mapped to VNIs", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlan": 100,
+							"trunk": [{ "id": 4 }],
+							"vxlanDevice": "vxlan0",
+							"vniMap": [{"vlan": 100, "vni": 10100}, {"vlan": 4, "vni": 10004}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.VniMap).To(HaveLen(2))
+				})
+				It("Invalid configuration - vniMap without vxlanDevice", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlan": 100,
+							"vniMap": [{"vlan": 100, "vni": 10100}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VLAN is not mapped", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlan": 100,
+							"trunk": [{ "id": 4 }],
+							"vxlanDevice": "vxlan0",
+							"vniMap": [{"vlan": 100, "vni": 10100}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - duplicate VNI", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlan": 100,
+							"trunk": [{ "id": 4 }],
+							"vxlanDevice": "vxlan0",
+							"vniMap": [{"vlan": 100, "vni": 10100}, {"vlan": 4, "vni": 10100}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VNI out of range", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"vlan": 100,
+							"vxlanDevice": "vxlan0",
+							"vniMap": [{"vlan": 100, "vni": 16777216}]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("VF settings config checks", func() {
+				It("Valid configuration - VF settings", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"spoofchk": "off",
+							"trust": "on",
+							"link_state": "disable",
+							"macStrategy": "devlink",
+							"min_tx_rate": 100,
+							"max_tx_rate": 1000
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.SpoofChk).To(Equal("off"))
+					Expect(*pluginConf.MaxTxRate).To(Equal(1000))
+				})
+				It("Invalid configuration - invalid trust value", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trust": "yes"
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - unknown MAC strategy", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"macStrategy": "sysfs"
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - unknown link state", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"link_state": "up"
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - min_tx_rate greater than max_tx_rate", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"min_tx_rate": 1000,
+							"max_tx_rate": 100
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Bandwidth config checks", func() {
+				It("Valid configuration - bandwidth", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"runtimeConfig": {
+								"bandwidth": {"ingressRate": 1000000, "ingressBurst": 80000,
+									"egressRate": 2000000, "egressBurst": 160000}
+							}
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.RuntimeConfig.Bandwidth).To(Equal(&localtypes.BandwidthEntry{
+						IngressRate: 1000000, IngressBurst: 80000, EgressRate: 2000000, EgressBurst: 160000}))
+				})
+				It("Invalid configuration - rate without burst", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"runtimeConfig": {"bandwidth": {"egressRate": 2000000}}
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - rate too big", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"runtimeConfig": {"bandwidth": {"ingressRate": 400000000000, "ingressBurst": 80000}}
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+			Context("Static FDB config checks", func() {
+				It("Invalid configuration - learning enabled with static FDB", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"staticFdb": true,
+							"portFlags": {"learning": true}
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+			})
 			Context("Bridge config checks", func() {
 				configFmt := `{
 								"name": "mynet",
@@ -180,6 +687,41 @@ var _ = Describe("Config", func() {
 							pluginConf)).To(HaveOccurred())
 					})
 				})
+				When("Create bridge", func() {
+					It("Valid config - create bridge", func() {
+						data := []byte(`{
+								"name": "mynet",
+								"type": "accelerated-bridge",
+								"deviceID": "0000:af:06.1",
+								"bridge": "br1",
+								"createBridge": true,
+								"bridgeVlanProtocol": "802.1ad",
+								"bridgeMTU": 9000
+							}`)
+						Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+						Expect(pluginConf.ActualBridge).To(Equal("br1"))
+					})
+					It("Invalid config - multiple bridges", func() {
+						data := []byte(`{
+								"name": "mynet",
+								"type": "accelerated-bridge",
+								"deviceID": "0000:af:06.1",
+								"bridge": "br1, br2",
+								"createBridge": true
+							}`)
+						Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+					})
+					It("Invalid config - unknown VLAN protocol", func() {
+						data := []byte(`{
+								"name": "mynet",
+								"type": "accelerated-bridge",
+								"deviceID": "0000:af:06.1",
+								"createBridge": true,
+								"bridgeVlanProtocol": "802.1x"
+							}`)
+						Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+					})
+				})
 				When("Autodetect bridge", func() {
 					BeforeEach(func() {
 						mockNetlink.On("LinkByName", mock.Anything).Return(
@@ -223,6 +765,73 @@ var _ = Describe("Config", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+		When("DeviceID is SF auxiliary device", func() {
+			const existingSF = "mlx5_core.sf.2"
+			BeforeEach(func() {
+				mockSriovnet.On("GetUplinkRepresentorFromAux", existingSF).Return(existingPF, nil)
+				mockSriovnet.On("GetSfIndexByAuxDev", existingSF).Return(88, nil)
+			})
+			It("Valid configuration - SF", func() {
+				mockSriovnet.On("GetNetDevicesFromAux", existingSF).Return([]string{"enp175s0f1s2"}, nil)
+				data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "mlx5_core.sf.2",
+						"vlan": 100,
+						"mtu": 9000,
+						"mac": "b2:62:a1:91:4a:3e"
+						}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pluginConf.IsSF).To(BeTrue())
+				Expect(pluginConf.SFNum).To(Equal(88))
+				Expect(pluginConf.PFName).To(Equal(existingPF))
+				Expect(pluginConf.OrigVfState.HostIFName).To(Equal("enp175s0f1s2"))
+			})
+			It("Invalid configuration - SF without netdevice", func() {
+				mockSriovnet.On("GetNetDevicesFromAux", existingSF).Return([]string{}, nil)
+				data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "mlx5_core.sf.2"
+						}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).To(HaveOccurred())
+			})
+			It("Invalid configuration - VF settings for SF", func() {
+				mockSriovnet.On("GetNetDevicesFromAux", existingSF).Return([]string{"enp175s0f1s2"}, nil)
+				data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "mlx5_core.sf.2",
+						"trust": "on"
+						}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).To(HaveOccurred())
+			})
+			It("Invalid configuration - bindDriver for SF", func() {
+				mockSriovnet.On("GetNetDevicesFromAux", existingSF).Return([]string{"enp175s0f1s2"}, nil)
+				data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "mlx5_core.sf.2",
+						"bindDriver": "vfio-pci"
+						}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).To(HaveOccurred())
+			})
+			It("Invalid configuration - legacy MAC strategy for SF", func() {
+				mockSriovnet.On("GetNetDevicesFromAux", existingSF).Return([]string{"enp175s0f1s2"}, nil)
+				data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "mlx5_core.sf.2",
+						"macStrategy": "legacy"
+						}`)
+				err := conf.ParseConf(data, pluginConf)
+				Expect(err).To(HaveOccurred())
+			})
+		})
 	})
 
 	Context("Checking getVfInfo function", func() {
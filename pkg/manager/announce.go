@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/rs/zerolog/log"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+const (
+	// DefaultAnnounceInterval is a default interval between announcements in milliseconds
+	DefaultAnnounceInterval = 100
+	// DefaultDADTimeout is a default timeout of IPv6 DAD in seconds
+	DefaultDADTimeout = 10
+
+	dadPollInterval = 50 * time.Millisecond
+)
+
+// AnnounceAddresses waits for IPv6 DAD if waitForDAD is set and sends announceCount gratuitous ARPs
+// for IPv4 addresses and unsolicited NAs for IPv6 addresses from the container interface.
+// Must be called in the container network namespace. Failed announcements are logged only,
+// ADD fails if DAD fails or doesn't complete in time
+func (m *manager) AnnounceAddresses(conf *types.PluginConf, ifName string, ips []*current.IPConfig) error {
+	link, err := m.nLink.LinkByName(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get container interface %s: %v", ifName, err)
+	}
+
+	if conf.WaitForDAD {
+		if err = m.waitForDAD(link, ips, dadTimeout(conf)); err != nil {
+			return err
+		}
+	}
+
+	interval := time.Duration(conf.AnnounceInterval) * time.Millisecond
+	if conf.AnnounceInterval == 0 {
+		interval = DefaultAnnounceInterval * time.Millisecond
+	}
+	for i := 0; i < conf.AnnounceCount; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		for _, ipc := range ips {
+			ip := ipc.Address.IP
+			if ip.To4() != nil {
+				err = m.announcer.SendGratuitousARP(link, ip)
+			} else {
+				err = m.announcer.SendUnsolicitedNA(link, ip)
+			}
+			if err != nil {
+				log.Warn().Msgf("failed to announce address %s on %s: %v", ip, ifName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// waitForDAD waits until IPv6 addresses from the list leave tentative state
+func (m *manager) waitForDAD(link netlink.Link, ips []*current.IPConfig, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		addrs, err := m.nLink.AddrList(link, netlink.FAMILY_V6)
+		if err != nil {
+			return fmt.Errorf("failed to list addresses of %s: %v", link.Attrs().Name, err)
+		}
+		tentative := false
+		for i := range addrs {
+			if !hasIP(ips, addrs[i].IP) {
+				continue
+			}
+			if addrs[i].Flags&unix.IFA_F_DADFAILED != 0 {
+				return fmt.Errorf("DAD failed for address %s on %s", addrs[i].IP, link.Attrs().Name)
+			}
+			if addrs[i].Flags&unix.IFA_F_TENTATIVE != 0 {
+				tentative = true
+			}
+		}
+		if !tentative {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("DAD is not completed on %s after %v", link.Attrs().Name, timeout)
+		}
+		time.Sleep(dadPollInterval)
+	}
+}
+
+// dadTimeout returns DAD timeout from the configuration or the default one
+func dadTimeout(conf *types.PluginConf) time.Duration {
+	if conf.DADTimeout == 0 {
+		return DefaultDADTimeout * time.Second
+	}
+	return time.Duration(conf.DADTimeout) * time.Second
+}
+
+// hasIP returns true if the IP is one of the configured addresses
+func hasIP(ips []*current.IPConfig, ip net.IP) bool {
+	for _, ipc := range ips {
+		if ipc.Address.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"errors"
+	"net"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("Address announcements", func() {
+	var (
+		t               GinkgoTInterface
+		netconf         *types.PluginConf
+		mockedNl        *utilsMocks.Netlink
+		mockedAnnouncer *utilsMocks.Announcer
+		m               manager
+		link            *FakeLink
+		ips             []*current.IPConfig
+	)
+	ip4 := net.ParseIP("192.168.1.10")
+	ip6 := net.ParseIP("fd00::10")
+
+	BeforeEach(func() {
+		t = GinkgoT()
+		netconf = &types.PluginConf{
+			NetConf: types.NetConf{
+				AnnounceCount:    2,
+				AnnounceInterval: 1,
+			},
+		}
+		ips = []*current.IPConfig{
+			{Address: net.IPNet{IP: ip4, Mask: net.CIDRMask(24, 32)}},
+			{Address: net.IPNet{IP: ip6, Mask: net.CIDRMask(64, 128)}},
+		}
+		link = &FakeLink{netlink.LinkAttrs{Name: "net1", Index: 5}}
+		mockedNl = &utilsMocks.Netlink{}
+		mockedAnnouncer = &utilsMocks.Announcer{}
+		m = manager{nLink: mockedNl, announcer: mockedAnnouncer}
+		mockedNl.On("LinkByName", "net1").Return(link, nil)
+	})
+	AfterEach(func() {
+		mockedNl.AssertExpectations(t)
+		mockedAnnouncer.AssertExpectations(t)
+	})
+	Context("Checking AnnounceAddresses function", func() {
+		It("Sends announcements for IPv4 and IPv6 addresses", func() {
+			mockedAnnouncer.On("SendGratuitousARP", link, ip4).Return(nil).Twice()
+			mockedAnnouncer.On("SendUnsolicitedNA", link, ip6).Return(nil).Twice()
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).NotTo(HaveOccurred())
+		})
+		It("Failed announcement is ignored", func() {
+			netconf.AnnounceCount = 1
+			mockedAnnouncer.On("SendGratuitousARP", link, ip4).Return(errors.New("send failed")).Once()
+			mockedAnnouncer.On("SendUnsolicitedNA", link, ip6).Return(nil).Once()
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).NotTo(HaveOccurred())
+		})
+		It("Waits for DAD before announcements", func() {
+			netconf.AnnounceCount = 1
+			netconf.WaitForDAD = true
+			mockedNl.On("AddrList", link, netlink.FAMILY_V6).Return([]netlink.Addr{
+				{IPNet: &net.IPNet{IP: ip6}, Flags: unix.IFA_F_TENTATIVE},
+				{IPNet: &net.IPNet{IP: net.ParseIP("fe80::1")}, Flags: unix.IFA_F_TENTATIVE},
+			}, nil).Once()
+			mockedNl.On("AddrList", link, netlink.FAMILY_V6).Return([]netlink.Addr{
+				{IPNet: &net.IPNet{IP: ip6}},
+				// link-local address is not checked
+				{IPNet: &net.IPNet{IP: net.ParseIP("fe80::1")}, Flags: unix.IFA_F_TENTATIVE},
+			}, nil).Once()
+			mockedAnnouncer.On("SendGratuitousARP", link, ip4).Return(nil).Once()
+			mockedAnnouncer.On("SendUnsolicitedNA", link, ip6).Return(nil).Once()
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).NotTo(HaveOccurred())
+		})
+		It("DAD failed (failure)", func() {
+			netconf.WaitForDAD = true
+			mockedNl.On("AddrList", link, netlink.FAMILY_V6).Return([]netlink.Addr{
+				{IPNet: &net.IPNet{IP: ip6}, Flags: unix.IFA_F_TENTATIVE | unix.IFA_F_DADFAILED},
+			}, nil).Once()
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).To(HaveOccurred())
+		})
+		It("DAD timeout (failure)", func() {
+			netconf.WaitForDAD = true
+			netconf.DADTimeout = 1
+			mockedNl.On("AddrList", link, netlink.FAMILY_V6).Return([]netlink.Addr{
+				{IPNet: &net.IPNet{IP: ip6}, Flags: unix.IFA_F_TENTATIVE},
+			}, nil)
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).To(HaveOccurred())
+		})
+		It("Container interface not found (failure)", func() {
+			mockedNl.ExpectedCalls = nil
+			mockedNl.On("LinkByName", "net1").Return(nil, errors.New("not found"))
+			Expect(m.AnnounceAddresses(netconf, "net1", ips)).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+const (
+	// DefaultCarrierTimeout is a default timeout of waiting for carrier in seconds
+	DefaultCarrierTimeout = 10
+
+	repOperStatePollInterval = 50 * time.Millisecond
+	// linkUpdatesBuffer lets the subscription deliver pending updates after the wait is finished
+	linkUpdatesBuffer = 16
+)
+
+// WaitForCarrier waits until the VF in the pod netns has carrier and is operationally up,
+// then waits until the representor is operationally up on the host. Both sides share carrierTimeout,
+// the returned error names the side which is not up in time. Only the representor is checked
+// for a VF with userspace driver
+func (m *manager) WaitForCarrier(conf *types.PluginConf, podifName string, netns ns.NetNS) error {
+	timeout := time.Duration(conf.CarrierTimeout) * time.Second
+	if conf.CarrierTimeout == 0 {
+		timeout = DefaultCarrierTimeout * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	if !conf.IsUserspaceDriver {
+		if err := m.waitForPodLinkUp(podifName, netns, deadline); err != nil {
+			return err
+		}
+	}
+	return m.waitForRepresentorUp(conf.Representor, deadline)
+}
+
+// waitForPodLinkUp watches link updates in the pod netns until the link has carrier and is operationally up
+func (m *manager) waitForPodLinkUp(podifName string, netns ns.NetNS, deadline time.Time) error {
+	updates := make(chan netlink.LinkUpdate, linkUpdatesBuffer)
+	done := make(chan struct{})
+	defer close(done)
+
+	// subscription socket is opened in the pod netns, existing links are listed to get the current state
+	err := netns.Do(func(_ ns.NetNS) error {
+		return m.nLink.LinkSubscribeWithOptions(updates, done, netlink.LinkSubscribeOptions{ListExisting: true})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to link updates in pod netns %s: %v", netns.Path(), err)
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return fmt.Errorf("link updates subscription in pod netns %s is closed", netns.Path())
+			}
+			if update.Link == nil || update.Link.Attrs().Name != podifName {
+				continue
+			}
+			if isLinkUp(update.Link.Attrs()) {
+				return nil
+			}
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for VF %s in pod netns %s to get carrier and become operationally up",
+				podifName, netns.Path())
+		}
+	}
+}
+
+// waitForRepresentorUp polls operational state of the representor until it is up
+func (m *manager) waitForRepresentorUp(repName string, deadline time.Time) error {
+	for {
+		rep, err := m.nLink.LinkByName(repName)
+		if err != nil {
+			return fmt.Errorf("failed to get VF representor %s: %v", repName, err)
+		}
+		if rep.Attrs().OperState == netlink.OperUp {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for VF representor %s to become operationally up, state %s",
+				repName, rep.Attrs().OperState)
+		}
+		time.Sleep(repOperStatePollInterval)
+	}
+}
+
+// isLinkUp returns true if the link has carrier and is operationally up
+func isLinkUp(attrs *netlink.LinkAttrs) bool {
+	return attrs.RawFlags&unix.IFF_LOWER_UP != 0 && attrs.OperState == netlink.OperUp
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("Carrier", func() {
+	var (
+		t        GinkgoTInterface
+		netconf  *types.PluginConf
+		mockedNl *utilsMocks.Netlink
+		m        manager
+	)
+	repUp := &FakeLink{netlink.LinkAttrs{Name: "pf0vf3", OperState: netlink.OperUp}}
+	repDown := &FakeLink{netlink.LinkAttrs{Name: "pf0vf3", OperState: netlink.OperDown}}
+	// linkUpdates returns mock handler which sends the updates to the subscription channel
+	linkUpdates := func(updates ...netlink.LinkAttrs) func(mock.Arguments) {
+		return func(args mock.Arguments) {
+			ch := args.Get(0).(chan<- netlink.LinkUpdate)
+			for i := range updates {
+				ch <- netlink.LinkUpdate{Link: &FakeLink{updates[i]}}
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		t = GinkgoT()
+		netconf = &types.PluginConf{
+			NetConf: types.NetConf{
+				WaitForCarrier: true,
+				CarrierTimeout: 1,
+			},
+			Representor: "pf0vf3",
+		}
+		mockedNl = &utilsMocks.Netlink{}
+		m = manager{nLink: mockedNl}
+	})
+	AfterEach(func() {
+		mockedNl.AssertExpectations(t)
+	})
+	Context("Checking WaitForCarrier function", func() {
+		It("VF and representor are up", func() {
+			mockedNl.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything,
+				netlink.LinkSubscribeOptions{ListExisting: true}).Run(linkUpdates(
+				netlink.LinkAttrs{Name: "lo", OperState: netlink.OperUnknown},
+				netlink.LinkAttrs{Name: "net1", OperState: netlink.OperDown},
+				netlink.LinkAttrs{Name: "net1", OperState: netlink.OperUp, RawFlags: unix.IFF_UP | unix.IFF_LOWER_UP},
+			)).Return(nil).Once()
+			mockedNl.On("LinkByName", "pf0vf3").Return(repDown, nil).Once()
+			mockedNl.On("LinkByName", "pf0vf3").Return(repUp, nil).Once()
+			Expect(m.WaitForCarrier(netconf, "net1", newFakeNs())).NotTo(HaveOccurred())
+		})
+		It("VF has no carrier (failure)", func() {
+			mockedNl.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything, mock.Anything).Run(linkUpdates(
+				netlink.LinkAttrs{Name: "net1", OperState: netlink.OperLowerLayerDown, RawFlags: unix.IFF_UP},
+			)).Return(nil).Once()
+			err := m.WaitForCarrier(netconf, "net1", newFakeNs())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VF net1"))
+		})
+		It("Representor is not up (failure)", func() {
+			mockedNl.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything, mock.Anything).Run(linkUpdates(
+				netlink.LinkAttrs{Name: "net1", OperState: netlink.OperUp, RawFlags: unix.IFF_UP | unix.IFF_LOWER_UP},
+			)).Return(nil).Once()
+			mockedNl.On("LinkByName", "pf0vf3").Return(repDown, nil)
+			err := m.WaitForCarrier(netconf, "net1", newFakeNs())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("representor pf0vf3"))
+		})
+		It("Only representor is checked for VF with userspace driver", func() {
+			netconf.IsUserspaceDriver = true
+			mockedNl.On("LinkByName", "pf0vf3").Return(repUp, nil).Once()
+			Expect(m.WaitForCarrier(netconf, "", newFakeNs())).NotTo(HaveOccurred())
+		})
+		It("Failed to subscribe to link updates (failure)", func() {
+			mockedNl.On("LinkSubscribeWithOptions", mock.Anything, mock.Anything, mock.Anything).
+				Return(errors.New("some error")).Once()
+			Expect(m.WaitForCarrier(netconf, "net1", newFakeNs())).To(HaveOccurred())
+		})
+	})
+})
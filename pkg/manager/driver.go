@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+// BindVFDriver binds the VF to the driver from bindDriver option, the original driver is saved in the VF state.
+// The VF is handled as a VF with userspace driver after the rebind
+func (m *manager) BindVFDriver(conf *types.PluginConf) error {
+	if conf.BindDriver == "" {
+		return nil
+	}
+	driver, err := m.sysfs.GetDriver(conf.DeviceID)
+	if err != nil {
+		return err
+	}
+	conf.OrigVfState.Driver = driver
+
+	if driver != conf.BindDriver {
+		if err = m.sysfs.UnbindDriver(conf.DeviceID); err != nil {
+			return err
+		}
+		if err = m.sysfs.BindDriver(conf.DeviceID, conf.BindDriver); err != nil {
+			// try to give the VF back to the original driver
+			if driver != "" {
+				_ = m.sysfs.BindDriver(conf.DeviceID, driver)
+			}
+			return err
+		}
+		log.Info().Msgf("VF %s driver changed from %q to %q", conf.DeviceID, driver, conf.BindDriver)
+	}
+
+	conf.UserspaceDevice, err = utils.GetUserspaceDevicePath(conf.DeviceID, conf.BindDriver)
+	if err != nil {
+		// the VF can't be used by the application, give it back to the original driver
+		if restoreErr := m.RestoreVFDriver(conf); restoreErr != nil {
+			log.Warn().Msgf("failed to restore driver of VF %s: %v", conf.DeviceID, restoreErr)
+		}
+		return err
+	}
+	conf.IsUserspaceDriver = true
+	conf.UserspaceDriver = conf.BindDriver
+	return nil
+}
+
+// RestoreVFDriver binds the VF back to the driver which was used before BindVFDriver,
+// VF is left unbound if it had no driver
+func (m *manager) RestoreVFDriver(conf *types.PluginConf) error {
+	if conf.BindDriver == "" || conf.OrigVfState.Driver == conf.BindDriver {
+		return nil
+	}
+	if err := m.sysfs.UnbindDriver(conf.DeviceID); err != nil {
+		return err
+	}
+	if conf.OrigVfState.Driver == "" {
+		return nil
+	}
+	if err := m.sysfs.BindDriver(conf.DeviceID, conf.OrigVfState.Driver); err != nil {
+		return fmt.Errorf("failed to restore driver of VF %s: %v", conf.DeviceID, err)
+	}
+	log.Info().Msgf("VF %s driver restored to %q", conf.DeviceID, conf.OrigVfState.Driver)
+	return nil
+}
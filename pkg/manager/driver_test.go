@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("VF driver", func() {
+	var (
+		t           GinkgoTInterface
+		netconf     *types.PluginConf
+		mockedSysfs *utilsMocks.Sysfs
+		m           manager
+	)
+
+	BeforeEach(func() {
+		t = GinkgoT()
+		netconf = &types.PluginConf{
+			NetConf: types.NetConf{
+				DeviceID:   "0000:11:00.0",
+				BindDriver: "vfio-pci",
+			},
+		}
+		mockedSysfs = &utilsMocks.Sysfs{}
+		m = manager{sysfs: mockedSysfs}
+	})
+	AfterEach(func() {
+		mockedSysfs.AssertExpectations(t)
+	})
+	Context("Checking BindVFDriver function", func() {
+		It("bindDriver is not set", func() {
+			netconf.BindDriver = ""
+			Expect(m.BindVFDriver(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.IsUserspaceDriver).To(BeFalse())
+		})
+		It("Rebind VF from kernel driver", func() {
+			mockedSysfs.On("GetDriver", "0000:11:00.0").Return("mlx5_core", nil)
+			mockedSysfs.On("UnbindDriver", "0000:11:00.0").Return(nil)
+			mockedSysfs.On("BindDriver", "0000:11:00.0", "vfio-pci").Return(nil)
+			Expect(m.BindVFDriver(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.Driver).To(Equal("mlx5_core"))
+			Expect(netconf.IsUserspaceDriver).To(BeTrue())
+			Expect(netconf.UserspaceDriver).To(Equal("vfio-pci"))
+			Expect(netconf.UserspaceDevice).To(Equal("/dev/vfio/42"))
+		})
+		It("VF is already bound to vfio-pci", func() {
+			mockedSysfs.On("GetDriver", "0000:11:00.0").Return("vfio-pci", nil)
+			Expect(m.BindVFDriver(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.IsUserspaceDriver).To(BeTrue())
+			mockedSysfs.AssertNotCalled(t, "UnbindDriver", "0000:11:00.0")
+		})
+		It("Failed to bind VF, original driver is restored", func() {
+			mockedSysfs.On("GetDriver", "0000:11:00.0").Return("mlx5_core", nil)
+			mockedSysfs.On("UnbindDriver", "0000:11:00.0").Return(nil)
+			mockedSysfs.On("BindDriver", "0000:11:00.0", "vfio-pci").Return(errors.New("bind failed"))
+			mockedSysfs.On("BindDriver", "0000:11:00.0", "mlx5_core").Return(nil)
+			Expect(m.BindVFDriver(netconf)).To(HaveOccurred())
+			Expect(netconf.IsUserspaceDriver).To(BeFalse())
+		})
+		It("Failed to get userspace device, original driver is restored", func() {
+			// the VF has no IOMMU group
+			netconf.DeviceID = "0000:11:00.1"
+			mockedSysfs.On("GetDriver", "0000:11:00.1").Return("mlx5_core", nil)
+			mockedSysfs.On("UnbindDriver", "0000:11:00.1").Return(nil).Twice()
+			mockedSysfs.On("BindDriver", "0000:11:00.1", "vfio-pci").Return(nil).Once()
+			mockedSysfs.On("BindDriver", "0000:11:00.1", "mlx5_core").Return(nil).Once()
+			Expect(m.BindVFDriver(netconf)).To(HaveOccurred())
+			Expect(netconf.IsUserspaceDriver).To(BeFalse())
+		})
+	})
+	Context("Checking RestoreVFDriver function", func() {
+		It("Restore kernel driver", func() {
+			netconf.OrigVfState.Driver = "mlx5_core"
+			mockedSysfs.On("UnbindDriver", "0000:11:00.0").Return(nil)
+			mockedSysfs.On("BindDriver", "0000:11:00.0", "mlx5_core").Return(nil)
+			Expect(m.RestoreVFDriver(netconf)).NotTo(HaveOccurred())
+		})
+		It("VF was bound to vfio-pci before ADD", func() {
+			netconf.OrigVfState.Driver = "vfio-pci"
+			Expect(m.RestoreVFDriver(netconf)).NotTo(HaveOccurred())
+			mockedSysfs.AssertNotCalled(t, "UnbindDriver", "0000:11:00.0")
+		})
+		It("VF had no driver", func() {
+			mockedSysfs.On("UnbindDriver", "0000:11:00.0").Return(nil)
+			Expect(m.RestoreVFDriver(netconf)).NotTo(HaveOccurred())
+		})
+		It("Failed to bind kernel driver", func() {
+			netconf.OrigVfState.Driver = "mlx5_core"
+			mockedSysfs.On("UnbindDriver", "0000:11:00.0").Return(nil)
+			mockedSysfs.On("BindDriver", "0000:11:00.0", "mlx5_core").Return(errors.New("bind failed"))
+			Expect(m.RestoreVFDriver(netconf)).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,319 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	nl "github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+const (
+	// vlanUplinkLedgerFile stores uplink VLANs added by the plugin, access is protected by vlanUplinkLockFile
+	vlanUplinkLedgerFile = "/var/lib/cni/accelerated-bridge/vlan-uplink/ledger.json"
+	// vxlanVniLedgerFile stores VNI filter entries added by the plugin to VXLAN devices,
+	// access is protected by vlanUplinkLockFile
+	vxlanVniLedgerFile = "/var/lib/cni/accelerated-bridge/vlan-uplink/vni-ledger.json"
+)
+
+// VlanLedgerStore provides a way to load and save the uplink VLAN ledger,
+// should be used while vlanUplinkLock is held
+type VlanLedgerStore interface {
+	Load() (types.UplinkVlanLedger, error)
+	Save(ledger types.UplinkVlanLedger) error
+}
+
+type fileVlanLedgerStore struct {
+	path string
+	seed func() (types.UplinkVlanLedger, error)
+}
+
+// NewVlanLedgerStore returns an instance of file based uplink VLAN ledger store,
+// seed builds the initial ledger if the file doesn't exist, seed may be nil
+func NewVlanLedgerStore(path string, seed func() (types.UplinkVlanLedger, error)) VlanLedgerStore {
+	return &fileVlanLedgerStore{path: path, seed: seed}
+}
+
+// Load reads the ledger from the file. If the file doesn't exist, the ledger is built by seed and saved,
+// so it is seeded only once, empty ledger is returned if there is no seed
+func (s *fileVlanLedgerStore) Load() (types.UplinkVlanLedger, error) {
+	ledger := types.UplinkVlanLedger{}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s.seedLedger(ledger)
+		}
+		return nil, fmt.Errorf("failed to read uplink VLAN ledger %q: %v", s.path, err)
+	}
+	if err = json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("failed to parse uplink VLAN ledger %q: %v", s.path, err)
+	}
+	return ledger, nil
+}
+
+func (s *fileVlanLedgerStore) seedLedger(ledger types.UplinkVlanLedger) (types.UplinkVlanLedger, error) {
+	if s.seed == nil {
+		return ledger, nil
+	}
+	ledger, err := s.seed()
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed uplink VLAN ledger %q: %v", s.path, err)
+	}
+	if err = s.Save(ledger); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// Save writes the ledger to a temporary file and renames it to keep the ledger consistent
+func (s *fileVlanLedgerStore) Save(ledger types.UplinkVlanLedger) error {
+	data, err := json.Marshal(ledger)
+	if err != nil {
+		return err
+	}
+	dirpath := filepath.Dir(s.path)
+	if err = os.MkdirAll(dirpath, 0700); err != nil {
+		return fmt.Errorf("failed to create uplink VLAN ledger directory(%q): %v", dirpath, err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write uplink VLAN ledger %q: %v", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write uplink VLAN ledger %q: %v", s.path, err)
+	}
+	return nil
+}
+
+// seedUplinkVlanLedger builds the uplink VLAN ledger from cached attachments, it is used when the ledger
+// doesn't exist yet, e.g. after upgrade from plugin versions without the ledger. VLANs of the attachments
+// which are configured on the uplinks are recorded as owned by the attachments, so they are removed
+// when the last attachment which uses them is deleted
+func (m *manager) seedUplinkVlanLedger() (types.UplinkVlanLedger, error) {
+	refs, err := m.cache.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached attachments: %v", err)
+	}
+
+	ledger := types.UplinkVlanLedger{}
+	var bridgeVlans map[int32][]*nl.BridgeVlanInfo
+	for _, ref := range refs {
+		conf := &types.PluginConf{}
+		if err = m.cache.Load(ref, conf); err != nil {
+			log.Warn().Msgf("Failed to load cached attachment %s, skip it: %v", ref, err)
+			continue
+		}
+		if !conf.SetUplinkVlan && conf.VxlanDevice == "" {
+			continue
+		}
+		// attachments cached by plugin versions without the ledger have no AttachmentID
+		if conf.AttachmentID == "" {
+			conf.AttachmentID = string(ref)
+		}
+		uplink, uplinkErr := m.getUplink(conf)
+		if uplinkErr != nil {
+			log.Warn().Msgf("Failed to get uplink of cached attachment %s, skip it: %v", ref, uplinkErr)
+			continue
+		}
+		if bridgeVlans == nil {
+			if bridgeVlans, err = utils.BridgeVlanList(m.nLink); err != nil {
+				return nil, fmt.Errorf("failed to list bridge VLANs: %v", err)
+			}
+		}
+		for _, vlan := range uplinkVlans(conf) {
+			if hasBridgeVlan(bridgeVlans[int32(uplink.Attrs().Index)], vlan) {
+				ledgerAddOwner(ledger, uplink.Attrs().Name, vlan, conf.AttachmentID)
+			}
+		}
+	}
+	log.Info().Msgf("Seeded uplink VLAN ledger from %d cached attachments", len(refs))
+	return ledger, nil
+}
+
+// GetUplinkVlanLedger returns the uplink VLAN ledger
+func (m *manager) GetUplinkVlanLedger() (types.UplinkVlanLedger, error) {
+	if err := m.vlanUplinkLock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to create uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+	defer func() {
+		_ = m.vlanUplinkLock.Unlock()
+	}()
+	return m.vlanLedger.Load()
+}
+
+// RepairUplinkVlanLedger removes owners for which isAttachment returns false from the uplink VLAN ledger
+// and the VNI ledger, deletes VLANs and VNI filter entries which have no owners left from the devices.
+// isAttachment is called while the ledger lock is held, so ADD and DEL can't change the ledgers between
+// the check and the ledger write. The ledgers are not changed if isAttachment fails
+func (m *manager) RepairUplinkVlanLedger(isAttachment func(owner string) (bool, error)) error {
+	if err := m.vlanUplinkLock.Lock(); err != nil {
+		return fmt.Errorf("failed to create uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+	defer func() {
+		_ = m.vlanUplinkLock.Unlock()
+	}()
+
+	vlanLedger, err := m.vlanLedger.Load()
+	if err != nil {
+		return err
+	}
+	vniLedger, err := m.vniLedger.Load()
+	if err != nil {
+		return err
+	}
+	validOwners, err := ledgerValidOwners(isAttachment, vlanLedger, vniLedger)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	delvlans := ledgerRemoveStaleOwners(vlanLedger, validOwners, "VLAN")
+	for _, uplinkName := range sortedLedgerUplinks(delvlans) {
+		uplink, linkErr := m.nLink.LinkByName(uplinkName)
+		if linkErr != nil {
+			log.Warn().Msgf("Failed to lookup uplink %s, skip VLANs removal: %v", uplinkName, linkErr)
+			continue
+		}
+		vlans := sortedLedgerVlans(delvlans, uplinkName)
+		log.Info().Msgf("Deleting VLANs for uplink %s: %v", uplinkName, vlans)
+		if delErr := utils.BridgeTrunkVlanDel(m.nLink, uplink, vlans); delErr != nil {
+			errs = append(errs, fmt.Errorf("failed to delete VLANs from interface %s: %v - %v",
+				uplinkName, vlans, delErr))
+		}
+	}
+	delvnis := ledgerRemoveStaleOwners(vniLedger, validOwners, "VNI")
+	for _, vxlanName := range sortedLedgerUplinks(delvnis) {
+		vxlan, linkErr := m.nLink.LinkByName(vxlanName)
+		if linkErr != nil {
+			log.Warn().Msgf("Failed to lookup VXLAN device %s, skip VNI filter entries removal: %v",
+				vxlanName, linkErr)
+			continue
+		}
+		for _, vni := range sortedLedgerVlans(delvnis, vxlanName) {
+			log.Info().Msgf("Removing VNI filter entry %d from %s", vni, vxlanName)
+			if delErr := m.nLink.VxlanVniDel(vxlan, uint32(vni)); delErr != nil {
+				errs = append(errs, fmt.Errorf("failed to remove VNI filter entry %d from %s: %v",
+					vni, vxlanName, delErr))
+			}
+		}
+	}
+
+	if err = m.vlanLedger.Save(vlanLedger); err != nil {
+		errs = append(errs, err)
+	}
+	if err = m.vniLedger.Save(vniLedger); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ledgerRemoveStaleOwners removes owners which are not valid from the ledger,
+// returns IDs which have no owners left, the result has the same format as the ledger
+func ledgerRemoveStaleOwners(ledger types.UplinkVlanLedger, validOwners map[string]bool,
+	kind string) types.UplinkVlanLedger {
+	removed := types.UplinkVlanLedger{}
+	for _, device := range sortedLedgerUplinks(ledger) {
+		for _, id := range sortedLedgerVlans(ledger, device) {
+			for _, owner := range append([]string(nil), ledger[device][id]...) {
+				if validOwners[owner] {
+					continue
+				}
+				log.Info().Msgf("Removing stale owner %s of %s %d on %s", owner, kind, id, device)
+				if ledgerRemoveOwner(ledger, device, id, owner) {
+					ledgerAddOwner(removed, device, id, owner)
+				}
+			}
+		}
+	}
+	return removed
+}
+
+// ledgerValidOwners checks each owner recorded in the ledgers once, returns owners which are attachments
+func ledgerValidOwners(isAttachment func(owner string) (bool, error),
+	ledgers ...types.UplinkVlanLedger) (map[string]bool, error) {
+	validOwners := map[string]bool{}
+	checked := map[string]bool{}
+	for _, ledger := range ledgers {
+		for _, device := range sortedLedgerUplinks(ledger) {
+			for _, id := range sortedLedgerVlans(ledger, device) {
+				for _, owner := range ledger[device][id] {
+					if checked[owner] {
+						continue
+					}
+					checked[owner] = true
+					valid, err := isAttachment(owner)
+					if err != nil {
+						return nil, fmt.Errorf("failed to check owner %s of %d on %s: %v", owner, id, device, err)
+					}
+					validOwners[owner] = valid
+				}
+			}
+		}
+	}
+	return validOwners, nil
+}
+
+// ledgerAddOwner adds owner of the uplink VLAN, returns false if the owner is already recorded
+func ledgerAddOwner(ledger types.UplinkVlanLedger, uplink string, vlan int, owner string) bool {
+	vlans, ok := ledger[uplink]
+	if !ok {
+		vlans = map[int][]string{}
+		ledger[uplink] = vlans
+	}
+	for _, o := range vlans[vlan] {
+		if o == owner {
+			return false
+		}
+	}
+	vlans[vlan] = append(vlans[vlan], owner)
+	return true
+}
+
+// ledgerRemoveOwner removes owner of the uplink VLAN,
+// returns true if the owner was recorded and the VLAN has no owners left
+func ledgerRemoveOwner(ledger types.UplinkVlanLedger, uplink string, vlan int, owner string) bool {
+	owners := ledger[uplink][vlan]
+	for i, o := range owners {
+		if o != owner {
+			continue
+		}
+		owners = append(owners[:i:i], owners[i+1:]...)
+		if len(owners) > 0 {
+			ledger[uplink][vlan] = owners
+			return false
+		}
+		delete(ledger[uplink], vlan)
+		if len(ledger[uplink]) == 0 {
+			delete(ledger, uplink)
+		}
+		return true
+	}
+	return false
+}
+
+// sortedLedgerVlans returns VLANs of the uplink from the ledger in ascending order
+func sortedLedgerVlans(ledger types.UplinkVlanLedger, uplink string) []int {
+	vlans := make([]int, 0, len(ledger[uplink]))
+	for vlan := range ledger[uplink] {
+		vlans = append(vlans, vlan)
+	}
+	sort.Ints(vlans)
+	return vlans
+}
+
+// sortedLedgerUplinks returns uplinks from the ledger in alphabetical order
+func sortedLedgerUplinks(ledger types.UplinkVlanLedger) []string {
+	uplinks := make([]string, 0, len(ledger))
+	for uplink := range ledger {
+		uplinks = append(uplinks, uplink)
+	}
+	sort.Strings(uplinks)
+	return uplinks
+}
@@ -0,0 +1,251 @@
+package manager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+	nl "github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
+	cacheMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache/mocks"
+	mgrMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager/mocks"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("VlanLedger", func() {
+	var (
+		t GinkgoTInterface
+	)
+	BeforeEach(func() {
+		t = GinkgoT()
+	})
+
+	Context("Checking file store", func() {
+		var testpath string
+		BeforeEach(func() {
+			var err error
+			testpath, err = os.MkdirTemp("", "accel-br-ledgertest")
+			check(err)
+		})
+		AfterEach(func() {
+			err := os.RemoveAll(testpath)
+			check(err)
+		})
+		It("Load not existing ledger (success)", func() {
+			store := NewVlanLedgerStore(filepath.Join(testpath, "ledger.json"), nil)
+			ledger, err := store.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ledger).To(BeEmpty())
+		})
+		It("Save and load ledger with new path (success)", func() {
+			store := NewVlanLedgerStore(filepath.Join(testpath, "subdir", "ledger.json"), nil)
+			ledger := types.UplinkVlanLedger{"bond0": {100: {"net1-a-eth0", "net1-b-eth0"}, 4: {"net1-a-eth0"}}}
+			Expect(store.Save(ledger)).NotTo(HaveOccurred())
+			loaded, err := store.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(ledger))
+		})
+		It("Load broken ledger (failure)", func() {
+			path := filepath.Join(testpath, "ledger.json")
+			check(os.WriteFile(path, []byte("{broken"), 0600))
+			_, err := NewVlanLedgerStore(path, nil).Load()
+			Expect(err).To(HaveOccurred())
+		})
+		It("Load not existing ledger with seed, the ledger is seeded once (success)", func() {
+			seeded := 0
+			seed := func() (types.UplinkVlanLedger, error) {
+				seeded++
+				return types.UplinkVlanLedger{"bond0": {100: {"net1-a-eth0"}}}, nil
+			}
+			store := NewVlanLedgerStore(filepath.Join(testpath, "ledger.json"), seed)
+			for i := 0; i < 2; i++ {
+				ledger, err := store.Load()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ledger).To(Equal(types.UplinkVlanLedger{"bond0": {100: {"net1-a-eth0"}}}))
+			}
+			Expect(seeded).To(Equal(1))
+		})
+		It("Load not existing ledger with failed seed (failure)", func() {
+			path := filepath.Join(testpath, "ledger.json")
+			seed := func() (types.UplinkVlanLedger, error) {
+				return nil, errors.New("some error")
+			}
+			_, err := NewVlanLedgerStore(path, seed).Load()
+			Expect(err).To(HaveOccurred())
+			Expect(path).NotTo(BeAnExistingFile())
+		})
+	})
+	Context("Checking upgrade from plugin versions without the ledger", func() {
+		var (
+			testpath string
+			confs    map[cache.StateRef]*types.PluginConf
+		)
+		BeforeEach(func() {
+			var err error
+			testpath, err = os.MkdirTemp("", "accel-br-ledgertest")
+			check(err)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+			// attachments cached by the old plugin have no AttachmentID
+			confs = map[cache.StateRef]*types.PluginConf{
+				"net1-a-eth0": {NetConf: types.NetConf{Vlan: 100, SetUplinkVlan: true}, PFName: "enp175s0f1",
+					Trunk: []int{4}},
+				"net1-b-eth0": {NetConf: types.NetConf{Vlan: 4, SetUplinkVlan: true}, PFName: "enp175s0f1"},
+				// VLANs of the attachment without uplink VLANs are not recorded
+				"net2-c-eth0": {NetConf: types.NetConf{Vlan: 200}, PFName: "enp175s0f1"},
+				"net3-d-eth0": {NetConf: types.NetConf{Vlan: 300, SetUplinkVlan: true}, PFName: "enp175s0f1"},
+			}
+		})
+		AfterEach(func() {
+			err := os.RemoveAll(testpath)
+			check(err)
+		})
+		// mockCache returns mocked cache with the cached attachments, load of net3-d-eth0 fails
+		mockCache := func() *cacheMocks.StateCache {
+			mockedCache := &cacheMocks.StateCache{}
+			mockedCache.On("List", "").Return(
+				[]cache.StateRef{"net1-a-eth0", "net1-b-eth0", "net2-c-eth0", "net3-d-eth0"}, nil)
+			mockedCache.On("Load", cache.StateRef("net3-d-eth0"), mock.Anything).Return(errors.New("some error"))
+			mockedCache.On("Load", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				conf := args.Get(1).(*types.PluginConf)
+				*conf = *confs[args.Get(0).(cache.StateRef)]
+			}).Return(nil)
+			return mockedCache
+		}
+		It("Seeding the ledger from cached attachments (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20}}
+
+			mockedNl.On("LinkByName", "enp175s0f1").Return(fakeUpLink, nil)
+			// VLAN 200 is added by admin, VLAN 4 was removed from the uplink
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				20: {{Vid: 100}, {Vid: 200}},
+			}, nil)
+
+			m := manager{nLink: mockedNl, cache: mockCache()}
+			ledger, err := m.seedUplinkVlanLedger()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ledger).To(Equal(types.UplinkVlanLedger{"enp175s0f1": {100: {"net1-a-eth0"}}}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Deleting attachment cached by the old plugin removes VLANs not used by other attachments (success)",
+			func() {
+				mockedNl := &utilsMocks.Netlink{}
+				mockedLock := &mgrMocks.IPCLock{}
+				fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20}}
+
+				mockedNl.On("LinkByName", "enp175s0f1").Return(fakeUpLink, nil)
+				mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+					20: {{Vid: 4}, {Vid: 100}, {Vid: 200}},
+				}, nil)
+				mockedLock.On("Lock").Return(nil)
+				mockedNl.On("BridgeVlanDel", fakeUpLink, uint16(100), false, false, false, true).Return(nil)
+				mockedLock.On("Unlock").Return(nil)
+
+				m := &manager{nLink: mockedNl, cache: mockCache(), vlanUplinkLock: mockedLock}
+				m.vlanLedger = NewVlanLedgerStore(filepath.Join(testpath, "ledger.json"), m.seedUplinkVlanLedger)
+				conf := *confs["net1-a-eth0"]
+				conf.AttachmentID = "net1-a-eth0"
+				Expect(m.deleteUplinkVlans(&conf)).NotTo(HaveOccurred())
+				mockedNl.AssertExpectations(t)
+				mockedNl.AssertNotCalled(t, "BridgeVlanDel", fakeUpLink, uint16(4), false, false, false, true)
+
+				ledger, err := m.vlanLedger.Load()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ledger).To(Equal(types.UplinkVlanLedger{"enp175s0f1": {4: {"net1-b-eth0"}}}))
+			})
+	})
+	Context("Checking ledger owners", func() {
+		It("Adding and removing owners", func() {
+			ledger := types.UplinkVlanLedger{}
+			Expect(ledgerAddOwner(ledger, "bond0", 100, "a")).To(BeTrue())
+			Expect(ledgerAddOwner(ledger, "bond0", 100, "b")).To(BeTrue())
+			Expect(ledgerAddOwner(ledger, "bond0", 100, "a")).To(BeFalse())
+			Expect(ledger).To(Equal(types.UplinkVlanLedger{"bond0": {100: {"a", "b"}}}))
+
+			Expect(ledgerRemoveOwner(ledger, "bond0", 100, "c")).To(BeFalse())
+			Expect(ledgerRemoveOwner(ledger, "bond0", 100, "a")).To(BeFalse())
+			Expect(ledgerRemoveOwner(ledger, "bond0", 100, "b")).To(BeTrue())
+			Expect(ledger).To(BeEmpty())
+			Expect(ledgerRemoveOwner(ledger, "bond0", 100, "b")).To(BeFalse())
+		})
+	})
+	Context("Checking RepairUplinkVlanLedger function", func() {
+		BeforeEach(func() {
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		It("Removes stale owners and VLANs without owners (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			mockedVniLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBondUpLink := &FakeBondLink{netlink.LinkAttrs{Name: "bond0", Index: 30}}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan1", Index: 40}}
+
+			locked := false
+			mockedLock.On("Lock").Run(func(_ mock.Arguments) { locked = true }).Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{
+				"bond0":  {4: {"stale"}, 6: {"valid", "stale"}, 100: {"stale"}},
+				"vxlan0": {200: {"stale"}},
+			}, nil)
+			mockedNl.On("LinkByName", "bond0").Return(fakeBondUpLink, nil)
+			mockedNl.On("BridgeVlanDel", fakeBondUpLink, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeBondUpLink, uint16(100), false, false, false, true).Return(nil)
+			// uplink was removed
+			mockedNl.On("LinkByName", "vxlan0").Return(nil, netlink.LinkNotFoundError{})
+			mockedLedger.On("Save", types.UplinkVlanLedger{"bond0": {6: {"valid"}}}).Return(nil)
+			mockedVniLedger.On("Load").Return(types.UplinkVlanLedger{
+				"vxlan1": {5000: {"stale"}, 5001: {"valid"}, 5002: {"stale", "other"}},
+			}, nil)
+			mockedNl.On("LinkByName", "vxlan1").Return(fakeVxlan, nil)
+			mockedNl.On("VxlanVniDel", fakeVxlan, uint32(5000)).Return(nil)
+			mockedNl.On("VxlanVniDel", fakeVxlan, uint32(5002)).Return(nil)
+			mockedVniLedger.On("Save", types.UplinkVlanLedger{"vxlan1": {5001: {"valid"}}}).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger,
+				vniLedger: mockedVniLedger}
+			checked := map[string]int{}
+			isAttachment := func(owner string) (bool, error) {
+				Expect(locked).To(BeTrue())
+				checked[owner]++
+				return owner == "valid", nil
+			}
+			Expect(m.RepairUplinkVlanLedger(isAttachment)).NotTo(HaveOccurred())
+			Expect(checked).To(Equal(map[string]int{"valid": 1, "stale": 1, "other": 1}))
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedVniLedger.AssertExpectations(t)
+		})
+		It("Owner check failed, the ledger is not changed (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			mockedVniLedger := &mgrMocks.VlanLedgerStore{}
+
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"bond0": {4: {"stale"}}}, nil)
+			mockedVniLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger,
+				vniLedger: mockedVniLedger}
+			isAttachment := func(_ string) (bool, error) {
+				return false, errors.New("some error")
+			}
+			Expect(m.RepairUplinkVlanLedger(isAttachment)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertNotCalled(t, "Save", mock.Anything)
+			mockedVniLedger.AssertNotCalled(t, "Save", mock.Anything)
+			mockedLock.AssertCalled(t, "Unlock")
+		})
+	})
+})
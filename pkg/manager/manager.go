@@ -1,29 +1,55 @@
 package manager
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/gofrs/flock"
 	"github.com/rs/zerolog/log"
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
 )
 
 const (
 	vlanUplinkLockFile = "/var/lib/cni/accelerated-bridge/vlan-uplink.lock"
+	// hwTCOffloadFeature is an ethtool feature which indicates that tc filters can be offloaded to HW
+	hwTCOffloadFeature = "hw-tc-offload"
+	// bandwidthFilterPrio is a priority of tc filters used for bandwidth limits
+	bandwidthFilterPrio = 1
+	// bandwidthFilterHandle is a handle of tc filters used for bandwidth limits
+	bandwidthFilterHandle = 1
+	// cTagFilterPrio is a priority of tc filters which pass C-tags of the representor in 802.1ad mode
+	cTagFilterPrio = 2
+	// cTagDropFilterPrio is a priority of tc filter which drops frames with other C-tags in 802.1ad mode
+	cTagDropFilterPrio = 3
+	// cTagDropFilterHandle is a handle of tc filter which drops frames with other C-tags
+	cTagDropFilterHandle = 1
+	// portFlagLearning is a name of the bridge port learning flag in sysfs
+	portFlagLearning = "learning"
+	// portFlagVlanTunnel is a name of the bridge port flag in sysfs which enables VLAN to tunnel mappings
+	portFlagVlanTunnel = "vlan_tunnel"
+	// vfSettingOn is a value of VF on/off settings, e.g. spoofchk or trust, which enables the setting
+	vfSettingOn = "on"
 )
 
 // IPCLock provides a way to lock and unlock around critical sections given each CNI instance
 // runs as a separate process.  Currently this is with a lockfile.
 type IPCLock interface {
 	Lock() error
+	TryLock() (bool, error)
 	Unlock() error
 }
 
@@ -40,12 +66,28 @@ func NewIPCLock(lockfile string) IPCLock {
 }
 
 func (l ipclock) Lock() error {
+	if err := l.createDir(); err != nil {
+		return err
+	}
+
+	return l.lock.Lock()
+}
+
+// TryLock takes the lock without blocking, returns false if the lock is held by another instance
+func (l ipclock) TryLock() (bool, error) {
+	if err := l.createDir(); err != nil {
+		return false, err
+	}
+
+	return l.lock.TryLock()
+}
+
+func (l ipclock) createDir() error {
 	dirpath := filepath.Dir(l.lock.Path())
 	if err := os.MkdirAll(dirpath, 0700); err != nil {
 		return fmt.Errorf("failed to create lock directory(%q): %v", dirpath, err)
 	}
-
-	return l.lock.Lock()
+	return nil
 }
 
 func (l ipclock) Unlock() error {
@@ -56,25 +98,56 @@ func (l ipclock) Unlock() error {
 type Manager interface {
 	SetupVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) (string, error)
 	ReleaseVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) error
+	RestoreVF(conf *types.PluginConf) error
+	IsVFInInitNetns(conf *types.PluginConf) bool
+	SetupUserspaceVF(conf *types.PluginConf) (string, error)
 	ResetVFConfig(conf *types.PluginConf) error
 	ApplyVFConfig(conf *types.PluginConf) error
+	BindVFDriver(conf *types.PluginConf) error
+	RestoreVFDriver(conf *types.PluginConf) error
+	Preflight(conf *types.PluginConf) error
 	AttachRepresentor(conf *types.PluginConf) error
+	AddStaticFdb(conf *types.PluginConf, mac string) error
+	FindFdbMACConflicts(conf *types.PluginConf) ([]string, error)
+	AnnounceAddresses(conf *types.PluginConf, ifName string, ips []*current.IPConfig) error
+	WaitForCarrier(conf *types.PluginConf, podifName string, netns ns.NetNS) error
 	DetachRepresentor(conf *types.PluginConf) error
+	CheckVF(conf *types.PluginConf, contIface *current.Interface, ips []*current.IPConfig, netns ns.NetNS) error
+	CheckRepresentor(conf *types.PluginConf) error
+	CheckStatus(bridges []string) error
+	GetHostInterfaces(conf *types.PluginConf) ([]*current.Interface, error)
+	GetUplinkVlanLedger() (types.UplinkVlanLedger, error)
+	RepairUplinkVlanLedger(isAttachment func(owner string) (bool, error)) error
 }
 
 type manager struct {
 	nLink          utils.Netlink
 	sriov          utils.SriovnetProvider
+	ethtool        utils.Ethtool
+	sysfs          utils.Sysfs
+	sysctl         utils.Sysctl
+	announcer      utils.Announcer
+	cache          cache.StateCache
 	vlanUplinkLock IPCLock
+	vlanLedger     VlanLedgerStore
+	vniLedger      VlanLedgerStore
 }
 
 // NewManager returns an instance of manager
 func NewManager() Manager {
-	return &manager{
+	m := &manager{
 		nLink:          &utils.NetlinkWrapper{},
 		sriov:          &utils.SriovnetWrapper{},
+		ethtool:        &utils.EthtoolWrapper{},
+		sysfs:          &utils.SysfsWrapper{},
+		sysctl:         &utils.SysctlWrapper{},
+		announcer:      &utils.AnnouncerWrapper{},
+		cache:          cache.NewStateCache(),
 		vlanUplinkLock: NewIPCLock(vlanUplinkLockFile),
+		vniLedger:      NewVlanLedgerStore(vxlanVniLedgerFile, nil),
 	}
+	m.vlanLedger = NewVlanLedgerStore(vlanUplinkLedgerFile, m.seedUplinkVlanLedger)
+	return m
 }
 
 // SetupVF sets up a VF in Pod netns
@@ -137,7 +210,12 @@ func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns n
 			return fmt.Errorf("error setting container interface name %s for %s", linkName, tempName)
 		}
 
-		// 7. Bring IF up in Pod netns
+		// 7. Apply per-interface sysctls before the IF is up, e.g. to disable RA before autoconfiguration
+		if err := m.applySysctl(conf, podifName); err != nil {
+			return err
+		}
+
+		// 8. Bring IF up in Pod netns
 		if err := m.nLink.LinkSetUp(linkObj); err != nil {
 			return fmt.Errorf("error bringing interface up in container ns: %q", err)
 		}
@@ -151,6 +229,23 @@ func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns n
 	return macAddress, nil
 }
 
+// applySysctl sets per-interface sysctls of the pod interface in sorted order of the keys,
+// the sysctls are reset by the kernel when the VF is moved back to the init netns
+func (m *manager) applySysctl(conf *types.PluginConf, podifName string) error {
+	keys := make([]string, 0, len(conf.Sysctl))
+	for key := range conf.Sysctl {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := m.sysctl.SetInterfaceSysctl(podifName, key, conf.Sysctl[key]); err != nil {
+			return err
+		}
+		log.Debug().Msgf("sysctl %s of %s set to %q", key, podifName, conf.Sysctl[key])
+	}
+	return nil
+}
+
 // ReleaseVF reset a VF from Pod netns and return it to init netns
 func (m *manager) ReleaseVF(conf *types.PluginConf, podifName, cid string, netns ns.NetNS) error {
 	initns, err := ns.GetCurrentNS()
@@ -215,6 +310,138 @@ func (m *manager) ReleaseVF(conf *types.PluginConf, podifName, cid string, netns
 	})
 }
 
+// RestoreVF restores original name, MAC and MTU of the VF which was returned to init netns
+// without ReleaseVF call, e.g. when container netns was destroyed before CmdDel
+func (m *manager) RestoreVF(conf *types.PluginConf) error {
+	linkName, err := m.getHostLinkName(conf)
+	if err != nil || linkName == "" {
+		return fmt.Errorf("failed to find netdevice for device %s in init netns: %v", conf.DeviceID, err)
+	}
+
+	linkObj, err := m.nLink.LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("failed to get netlink device with name %s: %q", linkName, err)
+	}
+
+	if err = m.nLink.LinkSetDown(linkObj); err != nil {
+		return fmt.Errorf("failed to set link %s down: %q", linkName, err)
+	}
+
+	if linkName != conf.OrigVfState.HostIFName {
+		if err = m.nLink.LinkSetName(linkObj, conf.OrigVfState.HostIFName); err != nil {
+			return fmt.Errorf("failed to rename link %s to host name %s: %q",
+				linkName, conf.OrigVfState.HostIFName, err)
+		}
+	}
+
+	// reset effective MAC address
+	if conf.MAC != "" {
+		var hwaddr net.HardwareAddr
+		hwaddr, err = net.ParseMAC(conf.OrigVfState.EffectiveMAC)
+		if err != nil {
+			return fmt.Errorf("failed to parse original effective MAC address %s: %v",
+				conf.OrigVfState.EffectiveMAC, err)
+		}
+		if err = m.nLink.LinkSetHardwareAddr(linkObj, hwaddr); err != nil {
+			return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v",
+				hwaddr, err)
+		}
+	}
+
+	// reset MTU
+	if conf.MTU != 0 {
+		if err = m.nLink.LinkSetMTU(linkObj, conf.OrigVfState.MTU); err != nil {
+			return fmt.Errorf("failed to set MTU on VF %s: %v", conf.OrigVfState.HostIFName, err)
+		}
+		log.Info().Msgf("VF link %s MTU set to %d", conf.OrigVfState.HostIFName, conf.OrigVfState.MTU)
+	}
+
+	return nil
+}
+
+// IsVFInInitNetns returns true if netdevice of the VF, SF or virtio vDPA device is in init netns,
+// the netdevice is returned to init netns by the kernel when container netns is destroyed
+func (m *manager) IsVFInInitNetns(conf *types.PluginConf) bool {
+	linkName, err := m.getHostLinkName(conf)
+	return err == nil && linkName != ""
+}
+
+// getHostLinkName returns current netdevice name of the VF, SF or virtio vDPA device in init netns
+func (m *manager) getHostLinkName(conf *types.PluginConf) (string, error) {
+	if conf.IsSF {
+		names, err := m.sriov.GetNetDevicesFromAux(conf.DeviceID)
+		if err != nil || len(names) == 0 {
+			return "", err
+		}
+		return names[0], nil
+	}
+	if conf.VdpaDriver == utils.VdpaDriverVirtio {
+		vdpaDev, err := utils.GetVdpaDevice(conf.DeviceID)
+		if err != nil || vdpaDev == nil {
+			return "", err
+		}
+		return vdpaDev.NetDev, nil
+	}
+	return utils.GetVFLinkName(conf.DeviceID)
+}
+
+// CheckVF validates that VF is present in Pod netns and has expected configuration
+func (m *manager) CheckVF(conf *types.PluginConf, contIface *current.Interface,
+	ips []*current.IPConfig, netns ns.NetNS) error {
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := m.nLink.LinkByName(contIface.Name)
+		if err != nil {
+			return fmt.Errorf("VF interface %s not found in container netns %s: %v",
+				contIface.Name, netns.Path(), err)
+		}
+
+		expectedMAC := conf.MAC
+		if expectedMAC == "" {
+			expectedMAC = contIface.Mac
+		}
+		if expectedMAC != "" {
+			var hwaddr net.HardwareAddr
+			hwaddr, err = net.ParseMAC(expectedMAC)
+			if err != nil {
+				return fmt.Errorf("failed to parse expected MAC address %s: %v", expectedMAC, err)
+			}
+			if linkObj.Attrs().HardwareAddr.String() != hwaddr.String() {
+				return fmt.Errorf("VF interface %s has MAC address %s, expected %s",
+					contIface.Name, linkObj.Attrs().HardwareAddr, hwaddr)
+			}
+		}
+
+		if conf.MTU != 0 && linkObj.Attrs().MTU != conf.MTU {
+			return fmt.Errorf("VF interface %s has MTU %d, expected %d",
+				contIface.Name, linkObj.Attrs().MTU, conf.MTU)
+		}
+
+		if len(ips) == 0 {
+			return nil
+		}
+		addrs, err := m.nLink.AddrList(linkObj, netlink.FAMILY_ALL)
+		if err != nil {
+			return fmt.Errorf("failed to list addresses of VF interface %s: %v", contIface.Name, err)
+		}
+		for _, ipc := range ips {
+			if !hasAddr(addrs, &ipc.Address) {
+				return fmt.Errorf("VF interface %s has no IP address %s", contIface.Name, ipc.Address.String())
+			}
+		}
+		return nil
+	})
+}
+
+// hasAddr returns true if addrs list contains an address equal to ipNet
+func hasAddr(addrs []netlink.Addr, ipNet *net.IPNet) bool {
+	for i := range addrs {
+		if addrs[i].IPNet != nil && addrs[i].IPNet.String() == ipNet.String() {
+			return true
+		}
+	}
+	return false
+}
+
 func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
 	attrs := link.Attrs()
 	for i := range attrs.Vfs {
@@ -227,6 +454,10 @@ func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
 
 // ApplyVFConfig configure a VF with parameters given in PluginConf
 func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
+	if conf.IsSF {
+		return m.applySFConfig(conf)
+	}
+
 	pfLink, err := m.nLink.LinkByName(conf.PFName)
 	if err != nil {
 		return fmt.Errorf("failed to lookup master %q: %v", conf.PFName, err)
@@ -239,6 +470,11 @@ func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
 	}
 
 	conf.OrigVfState.AdminMAC = vfState.Mac.String() // Save administrative MAC for restoring it later
+	conf.OrigVfState.SpoofChk = vfState.Spoofchk
+	conf.OrigVfState.Trust = vfState.Trust != 0
+	conf.OrigVfState.LinkState = vfState.LinkState
+	conf.OrigVfState.MinTxRate = int(vfState.MinTxRate)
+	conf.OrigVfState.MaxTxRate = int(vfState.MaxTxRate)
 
 	// Set mac address
 	if conf.MAC != "" {
@@ -248,14 +484,127 @@ func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
 			return fmt.Errorf("failed to parse MAC address %s: %v", conf.MAC, err)
 		}
 
-		if err = m.nLink.LinkSetVfHardwareAddr(pfLink, conf.VFID, hwaddr); err != nil {
+		var port *netlink.DevlinkPort
+		if port, err = m.getVfDevlinkPort(conf); err != nil {
+			return err
+		}
+		// port function hw_addr is the administrative MAC when the VF MAC is managed through devlink
+		if port != nil && len(port.Fn.HwAddr) > 0 {
+			conf.OrigVfState.AdminMAC = port.Fn.HwAddr.String()
+		}
+
+		if err = m.setVfMAC(conf, pfLink, port, hwaddr); err != nil {
 			return fmt.Errorf("failed to set MAC address to %s: %v", hwaddr, err)
 		}
 	}
 
+	return m.applyVfSettings(conf, pfLink)
+}
+
+// applySFConfig configures administrative MAC of the SF, SF MAC can be set only through devlink port function
+func (m *manager) applySFConfig(conf *types.PluginConf) error {
+	if conf.MAC == "" {
+		return nil
+	}
+	hwaddr, err := net.ParseMAC(conf.MAC)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", conf.MAC, err)
+	}
+
+	port, err := m.getVfDevlinkPort(conf)
+	if err != nil {
+		return err
+	}
+	conf.OrigVfState.AdminMAC = port.Fn.HwAddr.String()
+
+	if err = m.setVfMAC(conf, nil, port, hwaddr); err != nil {
+		return fmt.Errorf("failed to set MAC address to %s: %v", hwaddr, err)
+	}
+	return nil
+}
+
+// applyVfSettings applies VF administrative settings through the PF
+func (m *manager) applyVfSettings(conf *types.PluginConf, pfLink netlink.Link) error {
+	if conf.SpoofChk != "" {
+		if err := m.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.SpoofChk == vfSettingOn); err != nil {
+			return vfSettingError("spoofchk", conf.SpoofChk, conf.PFName, err)
+		}
+	}
+
+	if conf.Trust != "" {
+		if err := m.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.Trust == vfSettingOn); err != nil {
+			return vfSettingError("trust", conf.Trust, conf.PFName, err)
+		}
+	}
+
+	if conf.LinkState != "" {
+		state, err := utils.GetVfLinkState(conf.LinkState)
+		if err != nil {
+			return err
+		}
+		if err = m.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
+			return vfSettingError("link_state", conf.LinkState, conf.PFName, err)
+		}
+	}
+
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		// keep original value for the rate which is not configured
+		minRate, maxRate := conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate
+		if conf.MinTxRate != nil {
+			minRate = *conf.MinTxRate
+		}
+		if conf.MaxTxRate != nil {
+			maxRate = *conf.MaxTxRate
+		}
+		if err := m.nLink.LinkSetVfRate(pfLink, conf.VFID, minRate, maxRate); err != nil {
+			return vfSettingError("min_tx_rate/max_tx_rate", fmt.Sprintf("%d/%d", minRate, maxRate), conf.PFName, err)
+		}
+	}
+
+	return nil
+}
+
+// resetVfSettings restores original VF administrative settings which were changed by applyVfSettings
+func (m *manager) resetVfSettings(conf *types.PluginConf, pfLink netlink.Link) error {
+	orig := conf.OrigVfState
+	if conf.SpoofChk != "" {
+		if err := m.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, orig.SpoofChk); err != nil {
+			return vfSettingError("spoofchk", strconv.FormatBool(orig.SpoofChk), conf.PFName, err)
+		}
+	}
+
+	if conf.Trust != "" {
+		if err := m.nLink.LinkSetVfTrust(pfLink, conf.VFID, orig.Trust); err != nil {
+			return vfSettingError("trust", strconv.FormatBool(orig.Trust), conf.PFName, err)
+		}
+	}
+
+	if conf.LinkState != "" {
+		if err := m.nLink.LinkSetVfState(pfLink, conf.VFID, orig.LinkState); err != nil {
+			return vfSettingError("link_state", strconv.FormatUint(uint64(orig.LinkState), 10), conf.PFName, err)
+		}
+	}
+
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		if err := m.nLink.LinkSetVfRate(pfLink, conf.VFID, orig.MinTxRate, orig.MaxTxRate); err != nil {
+			return vfSettingError("min_tx_rate/max_tx_rate",
+				fmt.Sprintf("%d/%d", orig.MinTxRate, orig.MaxTxRate), conf.PFName, err)
+		}
+	}
+
 	return nil
 }
 
+// vfSettingError returns an error for VF setting which can't be applied,
+// switchdev drivers don't support some of the VF settings which are available in legacy mode
+func vfSettingError(setting, value, pfName string, err error) error {
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("VF setting %s %s is rejected by the driver of PF %s, "+
+			"the setting may be not supported in switchdev mode: %v", setting, value, pfName, err)
+	}
+	return fmt.Errorf("failed to set VF setting %s to %s: %v", setting, value, err)
+}
+
 // ResetVFConfig reset a VF to its original state
 func (m *manager) ResetVFConfig(conf *types.PluginConf) error {
 	pfLink, err := m.nLink.LinkByName(conf.PFName)
@@ -271,23 +620,90 @@ func (m *manager) ResetVFConfig(conf *types.PluginConf) error {
 			return fmt.Errorf("failed to parse original administrative MAC address %s: %v",
 				conf.OrigVfState.AdminMAC, err)
 		}
-		if err = m.nLink.LinkSetVfHardwareAddr(pfLink, conf.VFID, hwaddr); err != nil {
+		var port *netlink.DevlinkPort
+		if port, err = m.getVfDevlinkPort(conf); err != nil {
+			return err
+		}
+		if err = m.setVfMAC(conf, pfLink, port, hwaddr); err != nil {
 			return fmt.Errorf("failed to restore original administrative MAC address %s: %v", hwaddr, err)
 		}
 	}
 
-	return nil
+	return m.resetVfSettings(conf, pfLink)
+}
+
+// getVfDevlinkPort returns devlink port of the VF representor which supports port function,
+// nil is returned if the VF MAC should be set with the legacy way
+func (m *manager) getVfDevlinkPort(conf *types.PluginConf) (*netlink.DevlinkPort, error) {
+	if conf.MACStrategy == utils.MACStrategyLegacy {
+		return nil, nil
+	}
+	strict := conf.MACStrategy == utils.MACStrategyDevlink || conf.IsSF
+
+	ports, err := m.nLink.DevLinkGetAllPortList()
+	if err != nil {
+		if strict {
+			return nil, fmt.Errorf("failed to get devlink ports: %v", err)
+		}
+		log.Debug().Msgf("Failed to get devlink ports, use legacy way to set VF MAC: %v", err)
+		return nil, nil
+	}
+	for _, port := range ports {
+		if conf.Representor == "" || port.NetdeviceName != conf.Representor {
+			continue
+		}
+		if port.Fn != nil {
+			return port, nil
+		}
+		break
+	}
+	if strict {
+		return nil, fmt.Errorf("devlink port function is not available for representor %s of device %s on PF %s",
+			conf.Representor, conf.DeviceID, conf.PFName)
+	}
+	log.Debug().Msgf("Devlink port function is not available for representor %s, use legacy way to set VF MAC",
+		conf.Representor)
+	return nil, nil
+}
+
+// setVfMAC sets administrative MAC of the VF through devlink port function if the port is provided,
+// in auto mode the legacy way is used if the driver doesn't support port function hw_addr
+func (m *manager) setVfMAC(conf *types.PluginConf, pfLink netlink.Link, port *netlink.DevlinkPort,
+	hwaddr net.HardwareAddr) error {
+	if port != nil {
+		err := m.nLink.DevlinkPortFnSet(port.BusName, port.DeviceName, port.PortIndex,
+			netlink.DevlinkPortFnSetAttrs{FnAttrs: netlink.DevlinkPortFn{HwAddr: hwaddr}, HwAddrValid: true})
+		if err == nil {
+			return nil
+		}
+		if conf.MACStrategy == utils.MACStrategyDevlink || conf.IsSF || !errors.Is(err, unix.EOPNOTSUPP) {
+			return fmt.Errorf("failed to set hw_addr of devlink port %s/%s/%d: %v",
+				port.BusName, port.DeviceName, port.PortIndex, err)
+		}
+		log.Debug().Msgf("Devlink port function hw_addr is not supported, use legacy way to set VF MAC: %v", err)
+	}
+	return m.nLink.LinkSetVfHardwareAddr(pfLink, conf.VFID, hwaddr)
 }
 
 func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
-	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	bridge, err := m.getBridge(conf)
 	if err != nil {
-		return fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+		return err
+	}
+
+	if (conf.Vlan > 0 || len(conf.Trunk) > 0) && !isVlanFilteringEnabled(bridge) {
+		return fmt.Errorf("VLAN configuration requires vlan_filtering to be enabled on the bridge %s", conf.ActualBridge)
+	}
+
+	if conf.VlanProtocol != "" {
+		if err = checkBridgeVlanProtocol(conf); err != nil {
+			return err
+		}
 	}
 
-	conf.Representor, err = m.sriov.GetVfRepresentor(conf.PFName, conf.VFID)
+	conf.Representor, err = m.getRepresentor(conf)
 	if err != nil {
-		return fmt.Errorf("failed to get VF's %d representor on NIC %s: %v", conf.VFID, conf.PFName, err)
+		return err
 	}
 
 	var rep netlink.Link
@@ -313,8 +729,12 @@ func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 		return fmt.Errorf("failed to add representor %s to bridge: %v", conf.Representor, err)
 	}
 
+	filtersSet := false
 	defer func() {
 		if err != nil {
+			if filtersSet {
+				m.deleteRepresentorFilters(conf, rep)
+			}
 			_ = m.nLink.LinkSetNoMaster(rep)
 		}
 	}()
@@ -341,7 +761,20 @@ func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 		}
 	}
 
-	if conf.SetUplinkVlan {
+	if conf.PortFlags != nil {
+		if err = setPortFlags(conf); err != nil {
+			return fmt.Errorf("failed to set bridge port flags for representor %s: %v", conf.Representor, err)
+		}
+	}
+
+	if hasRepresentorFilters(conf) {
+		if err = m.setRepresentorFilters(conf, rep); err != nil {
+			return fmt.Errorf("failed to set tc filters for representor %s: %v", conf.Representor, err)
+		}
+		filtersSet = true
+	}
+
+	if conf.SetUplinkVlan || conf.VxlanDevice != "" {
 		if err = m.addUplinkVlans(conf); err != nil {
 			return fmt.Errorf("failed to add trunk VLANs to uplink %v", err)
 		}
@@ -350,165 +783,1087 @@ func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 	return nil
 }
 
-func (m *manager) addUplinkVlans(conf *types.PluginConf) error {
-	var uplink netlink.Link
-	var err error
-
-	uplink, err = m.nLink.LinkByName(conf.PFName)
-	if err != nil {
-		return fmt.Errorf("failed to lookup PF %s: %v", conf.PFName, err)
-	}
-
-	if bonduplink, bonderr := utils.GetParentBondForLink(m.nLink, uplink); bonderr == nil {
-		log.Debug().Msgf("Using bond master for adding uplink vlans: pf:%s - master:%s",
-			uplink.Attrs().Name, bonduplink.Attrs().Name)
-		uplink = bonduplink
+// getRepresentor returns name of the VF or SF representor
+func (m *manager) getRepresentor(conf *types.PluginConf) (string, error) {
+	if conf.IsSF {
+		rep, err := m.sriov.GetSfRepresentor(conf.PFName, conf.SFNum)
+		if err != nil {
+			return "", fmt.Errorf("failed to get SF's %d representor on NIC %s: %v", conf.SFNum, conf.PFName, err)
+		}
+		return rep, nil
 	}
-
-	var vlans []int
-	if len(conf.Trunk) > 0 {
-		vlans = conf.Trunk
+	rep, err := m.sriov.GetVfRepresentor(conf.PFName, conf.VFID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VF's %d representor on NIC %s: %v", conf.VFID, conf.PFName, err)
 	}
+	return rep, nil
+}
 
-	if conf.Vlan > 0 {
-		vlans = append(vlans, conf.Vlan)
+// getBridge returns the bridge link,
+// bridge is created if it doesn't exist and createBridge option is set
+func (m *manager) getBridge(conf *types.PluginConf) (netlink.Link, error) {
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err == nil {
+		return bridge, nil
 	}
-
-	err = m.vlanUplinkLock.Lock()
-	if err != nil {
-		return fmt.Errorf("failed to create uplink VLAN removal file lock: %s, %v", vlanUplinkLockFile, err)
+	if _, notFound := err.(netlink.LinkNotFoundError); !notFound || !conf.CreateBridge {
+		return nil, fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
 	}
-	defer func() {
-		_ = m.vlanUplinkLock.Unlock()
-	}()
+	return m.createBridge(conf)
+}
 
-	log.Info().Msgf("Setting VLANs for uplink %s: %v", uplink.Attrs().Name, vlans)
-	if err = utils.BridgeTrunkVlanAdd(m.nLink, uplink, vlans); err != nil {
-		return fmt.Errorf("failed to add VLANs to interface %s: %v - %v", uplink.Attrs().Name, vlans, err)
+// createBridge creates the bridge with vlan_filtering enabled,
+// sets VLAN protocol and attaches the uplink to the bridge if requested
+func (m *manager) createBridge(conf *types.PluginConf) (netlink.Link, error) {
+	vlanFiltering := true
+	log.Info().Msgf("Creating bridge %s", conf.ActualBridge)
+	err := m.nLink.LinkAdd(&netlink.Bridge{
+		LinkAttrs:     netlink.LinkAttrs{Name: conf.ActualBridge, MTU: conf.BridgeMTU},
+		VlanFiltering: &vlanFiltering,
+	})
+	// bridge can be created by concurrent ADD request
+	if err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to create bridge %s: %v", conf.ActualBridge, err)
 	}
 
-	return nil
-}
-
-func (m *manager) DetachRepresentor(conf *types.PluginConf) error {
-	rep, err := m.nLink.LinkByName(conf.Representor)
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
 	if err != nil {
-		return fmt.Errorf("failed to get representor %s link: %v", conf.Representor, err)
+		return nil, fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
 	}
 
-	if err = m.nLink.LinkSetDown(rep); err != nil {
-		return fmt.Errorf("failed to set representor %s down: %v", conf.Representor, err)
+	protoName := conf.BridgeVlanProtocol
+	if protoName == "" {
+		protoName = conf.VlanProtocol
 	}
-
-	// Restore MTU
-	if conf.MTU != 0 {
-		if err = m.nLink.LinkSetMTU(rep, conf.OrigRepState.MTU); err != nil {
-			return fmt.Errorf("failed to set MTU on rep %s: %v", conf.Representor, err)
+	if protoName != "" {
+		var proto uint16
+		if proto, err = utils.GetVlanProtocolID(protoName); err != nil {
+			return nil, err
+		}
+		if err = utils.SetBridgeVlanProtocol(conf.ActualBridge, proto); err != nil {
+			return nil, err
 		}
-		log.Info().Msgf("Restoring MTU %d on rep %s", conf.OrigRepState.MTU, conf.Representor)
-	}
-
-	log.Info().Msgf("Detaching rep %s from the bridge %s", conf.Representor, conf.ActualBridge)
-
-	if err = m.nLink.LinkSetNoMaster(rep); err != nil {
-		return fmt.Errorf("failed to detatch representor %s from bridge: %v", conf.Representor, err)
 	}
 
-	if conf.SetUplinkVlan {
-		if err = m.deleteUplinkVlans(conf); err != nil {
-			log.Warn().Msgf("Failed to delete trunk VLANs from uplink %v", err)
+	if conf.BridgeAttachUplink {
+		if err = m.attachUplink(conf, bridge); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	if err = m.nLink.LinkSetUp(bridge); err != nil {
+		return nil, fmt.Errorf("failed to set bridge %s up: %v", conf.ActualBridge, err)
+	}
+	return bridge, nil
 }
 
-func (m *manager) deleteUplinkVlans(conf *types.PluginConf) error {
-	var uplink netlink.Link
-	var err error
-
-	uplink, err = m.nLink.LinkByName(conf.PFName)
+// attachUplink attaches PF or its bond to the bridge
+func (m *manager) attachUplink(conf *types.PluginConf, bridge netlink.Link) error {
+	uplink, err := m.nLink.LinkByName(conf.PFName)
 	if err != nil {
 		return fmt.Errorf("failed to lookup PF %s: %v", conf.PFName, err)
 	}
-
-	if bonduplink, bonderr := utils.GetParentBondForLink(m.nLink, uplink); bonderr == nil {
-		log.Debug().Msgf("Using bond master for removing uplink vlans: pf:%s - master:%s",
-			uplink.Attrs().Name, bonduplink.Attrs().Name)
-		uplink = bonduplink
+	if bondUplink, bondErr := utils.GetParentBondForLink(m.nLink, uplink); bondErr == nil {
+		uplink = bondUplink
 	}
 
-	var vlans []int
-	if len(conf.Trunk) > 0 {
-		vlans = conf.Trunk
+	if uplink.Attrs().MasterIndex == bridge.Attrs().Index {
+		return nil
 	}
-
-	if conf.Vlan > 0 {
-		vlans = append(vlans, conf.Vlan)
+	if uplink.Attrs().MasterIndex != 0 {
+		return fmt.Errorf("uplink %s is already attached to another master", uplink.Attrs().Name)
 	}
 
-	var bridgeLink netlink.Link
-	bridgeLink, err = utils.GetParentBridgeForLink(m.nLink, uplink)
-	if err != nil {
-		return fmt.Errorf("failed to lookup bridge index for interface:%s: %d %v",
-			uplink.Attrs().Name, uplink.Attrs().MasterIndex, err)
+	log.Info().Msgf("Attaching uplink %s to the bridge %s", uplink.Attrs().Name, conf.ActualBridge)
+	if err = m.nLink.LinkSetMaster(uplink, bridge); err != nil {
+		return fmt.Errorf("failed to attach uplink %s to the bridge %s: %v", uplink.Attrs().Name, conf.ActualBridge, err)
 	}
+	return nil
+}
 
-	err = m.vlanUplinkLock.Lock()
+// checkBridgeVlanProtocol validates that VLAN protocol of the bridge matches configuration
+func checkBridgeVlanProtocol(conf *types.PluginConf) error {
+	expected, err := utils.GetVlanProtocolID(conf.VlanProtocol)
 	if err != nil {
-		return fmt.Errorf("failed to create uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+		return err
 	}
-	defer func() {
-		_ = m.vlanUplinkLock.Unlock()
-	}()
-
-	var currentbrif []netlink.Link
-	currentbrif, err = utils.GetBridgeLinks(m.nLink, bridgeLink)
+	actual, err := utils.GetBridgeVlanProtocol(conf.ActualBridge)
 	if err != nil {
-		return fmt.Errorf("failed to get bridge interfaces:%s: %v",
-			bridgeLink.Attrs().Name, err)
+		return err
 	}
-
-	// remove the uplink from the list of interfaces.  we only want to check for
-	// other rep ports using these vlans
-	var repbrif []netlink.Link
-	for _, link := range currentbrif {
-		if link.Attrs().Index != uplink.Attrs().Index {
-			repbrif = append(repbrif, link)
-		}
+	if actual != expected {
+		return fmt.Errorf("bridge %s has VLAN protocol %#04x, expected %s",
+			conf.ActualBridge, actual, conf.VlanProtocol)
 	}
+	return nil
+}
 
-	delvlans := m.getUnusedVlanList(repbrif, vlans)
+// isVlanFilteringEnabled returns true if the link is a bridge with vlan_filtering enabled
+func isVlanFilteringEnabled(link netlink.Link) bool {
+	bridge, ok := link.(*netlink.Bridge)
+	return ok && bridge.VlanFiltering != nil && *bridge.VlanFiltering
+}
 
-	log.Info().Msgf("Deleting VLANs for uplink %s: %v", uplink.Attrs().Name, delvlans)
-	if err = utils.BridgeTrunkVlanDel(m.nLink, uplink, delvlans); err != nil {
-		return fmt.Errorf("failed to delete VLANs from interface %s: %v - %v", uplink.Attrs().Name, delvlans, err)
+// portFlagsMap returns requested bridge port flags with their names in sysfs
+func portFlagsMap(flags *types.PortFlags) map[string]bool {
+	named := map[string]*bool{
+		portFlagLearning:  flags.Learning,
+		"unicast_flood":   flags.UnicastFlood,
+		"multicast_flood": flags.MulticastFlood,
+		"broadcast_flood": flags.BroadcastFlood,
+		"hairpin_mode":    flags.Hairpin,
+		"isolated":        flags.Isolated,
+		"neigh_suppress":  flags.NeighSuppress,
+		"bpdu_guard":      flags.BpduGuard,
+		"root_block":      flags.RootBlock,
 	}
-
-	return nil
+	result := make(map[string]bool)
+	for name, value := range named {
+		if value != nil {
+			result[name] = *value
+		}
+	}
+	return result
 }
 
-// check if any of the interfaces in the brif list are still using the vlans in the `vlans` list argument
-func (m *manager) getUnusedVlanList(brif []netlink.Link, vlans []int) (unusedVlans []int) {
-	var allbrif map[int32][]*nl.BridgeVlanInfo
-	allbrif, _ = utils.BridgeVlanList(m.nLink)
+// sortedFlagNames returns names of the flags in stable order
+func sortedFlagNames(flags map[string]bool) []string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setPortFlags sets bridge port flags for representor and saves original values to restore them on detach
+func setPortFlags(conf *types.PluginConf) error {
+	flags := portFlagsMap(conf.PortFlags)
+	conf.OrigRepState.PortFlags = make(map[string]bool, len(flags))
+	for _, name := range sortedFlagNames(flags) {
+		orig, err := utils.GetBridgePortFlag(conf.Representor, name)
+		if err != nil {
+			return err
+		}
+		conf.OrigRepState.PortFlags[name] = orig
+		log.Info().Msgf("Setting bridge port flag %s=%t for rep %s", name, flags[name], conf.Representor)
+		if err = utils.SetBridgePortFlag(conf.Representor, name, flags[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePortFlags restores original values of bridge port flags for representor
+func restorePortFlags(conf *types.PluginConf) error {
+	for _, name := range sortedFlagNames(conf.OrigRepState.PortFlags) {
+		if err := utils.SetBridgePortFlag(conf.Representor, name, conf.OrigRepState.PortFlags[name]); err != nil {
+			return err
+		}
+	}
+	log.Info().Msgf("Restoring bridge port flags on rep %s", conf.Representor)
+	return nil
+}
+
+// hasBandwidthLimits returns true if bandwidth limits are requested with runtime configuration
+func hasBandwidthLimits(conf *types.PluginConf) bool {
+	bw := conf.RuntimeConfig.Bandwidth
+	return bw != nil && (bw.IngressRate > 0 || bw.EgressRate > 0)
+}
+
+// hasRepresentorFilters returns true if tc filters should be added to the representor
+func hasRepresentorFilters(conf *types.PluginConf) bool {
+	return hasBandwidthLimits(conf) || len(conf.CTags) > 0
+}
+
+// setRepresentorFilters installs tc filters to the clsact qdisc of the representor:
+// matchall filters with police action for bandwidth limits and, in 802.1ad mode, filters which pass
+// only configured C-tags inside the service VLAN. Traffic sent by the pod is received on representor
+// ingress and traffic sent to the pod is transmitted on representor egress
+func (m *manager) setRepresentorFilters(conf *types.PluginConf, rep netlink.Link) error {
+	features, err := m.ethtool.Features(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get features of representor %s: %v", conf.Representor, err)
+	}
+	var flags uint32
+	if features[hwTCOffloadFeature] {
+		flags = utils.TCClsFlagsSkipSW
+	} else {
+		log.Warn().Msgf("%s is disabled for rep %s, tc filters will not be offloaded",
+			hwTCOffloadFeature, conf.Representor)
+	}
+
+	// clsact qdisc may be already added to the representor, e.g. by admin, it is kept on DEL in this case
+	qdisc := clsactQdisc(rep)
+	qdiscAdded := true
+	if err = m.nLink.QdiscAdd(qdisc); err != nil {
+		if !errors.Is(err, unix.EEXIST) {
+			return fmt.Errorf("failed to add clsact qdisc: %v", err)
+		}
+		log.Debug().Msgf("clsact qdisc already exists on rep %s", conf.Representor)
+		qdiscAdded = false
+	}
+	var added []netlink.Filter
+	defer func() {
+		if err != nil {
+			for _, f := range added {
+				_ = m.nLink.FilterDel(f)
+			}
+			if qdiscAdded {
+				_ = m.nLink.QdiscDel(qdisc)
+			}
+		}
+	}()
+
+	filters := representorFilters(conf, rep)
+	if !qdiscAdded {
+		if err = m.checkFilterPrios(rep, filters); err != nil {
+			return err
+		}
+	}
+
+	if bw := conf.RuntimeConfig.Bandwidth; hasBandwidthLimits(conf) {
+		log.Info().Msgf("Setting egress rate %d bps and ingress rate %d bps for rep %s",
+			bw.EgressRate, bw.IngressRate, conf.Representor)
+	}
+	if len(conf.CTags) > 0 {
+		log.Info().Msgf("Allowing C-tags %v inside service VLAN %d for rep %s", conf.CTags, conf.Vlan, conf.Representor)
+	}
+	for _, filter := range filters {
+		if err = m.addFilter(filter, flags); err != nil {
+			return fmt.Errorf("failed to add %s filter with priority %d on parent %s: %v", filter.Type(),
+				filter.Attrs().Priority, netlink.HandleStr(filter.Attrs().Parent), err)
+		}
+		added = append(added, filter)
+	}
+
+	conf.OrigRepState.ClsactAdded = qdiscAdded
+	return nil
+}
+
+// addFilter adds matchall or VLAN flower filter with classifier flags
+func (m *manager) addFilter(filter netlink.Filter, flags uint32) error {
+	switch f := filter.(type) {
+	case *netlink.MatchAll:
+		return m.nLink.MatchAllFilterAdd(f, flags)
+	case *vlanFlower:
+		return m.nLink.VlanFlowerFilterAdd(f.Flower, f.vlanID, flags)
+	}
+	return fmt.Errorf("unsupported filter type %s", filter.Type())
+}
+
+// checkFilterPrios returns error if the existing clsact qdisc already has filters with priorities
+// of the plugin filters, e.g. added by admin, the plugin doesn't replace or collide with such filters
+func (m *manager) checkFilterPrios(rep netlink.Link, filters []netlink.Filter) error {
+	existing := make(map[uint32][]netlink.Filter)
+	for _, filter := range filters {
+		parent := filter.Attrs().Parent
+		if _, listed := existing[parent]; !listed {
+			list, err := m.nLink.FilterList(rep, parent)
+			if err != nil {
+				return fmt.Errorf("failed to list tc filters: %v", err)
+			}
+			existing[parent] = list
+		}
+		for _, f := range existing[parent] {
+			if f.Attrs().Priority == filter.Attrs().Priority {
+				return fmt.Errorf("tc filter with priority %d already exists on parent %s",
+					filter.Attrs().Priority, netlink.HandleStr(parent))
+			}
+		}
+	}
+	return nil
+}
+
+// deleteRepresentorFilters removes tc filters added by the plugin from the representor,
+// clsact qdisc is removed only if it was added by the plugin
+func (m *manager) deleteRepresentorFilters(conf *types.PluginConf, rep netlink.Link) {
+	for _, filter := range representorFilters(conf, rep) {
+		if err := m.nLink.FilterDel(filter); err != nil {
+			log.Warn().Msgf("Failed to remove %s filter with priority %d on parent %s from rep %s: %v",
+				filter.Type(), filter.Attrs().Priority, netlink.HandleStr(filter.Attrs().Parent), conf.Representor, err)
+		}
+	}
+	if conf.OrigRepState.ClsactAdded {
+		if err := m.nLink.QdiscDel(clsactQdisc(rep)); err != nil {
+			log.Warn().Msgf("Failed to remove clsact qdisc from rep %s: %v", conf.Representor, err)
+		}
+	}
+}
+
+// representorFilters returns tc filters of the representor: egress limit is applied to the representor
+// ingress and ingress limit is applied to the representor egress, C-tags are filtered in both directions
+func representorFilters(conf *types.PluginConf, rep netlink.Link) []netlink.Filter {
+	var filters []netlink.Filter
+	if hasBandwidthLimits(conf) {
+		bw := conf.RuntimeConfig.Bandwidth
+		// conforming traffic is passed to C-tag filters
+		conform := netlink.TC_POLICE_OK
+		if len(conf.CTags) > 0 {
+			conform = netlink.TC_POLICE_PIPE
+		}
+		if bw.EgressRate > 0 {
+			filters = append(filters,
+				policeFilter(rep, netlink.HANDLE_MIN_INGRESS, bw.EgressRate, bw.EgressBurst, conform))
+		}
+		if bw.IngressRate > 0 {
+			filters = append(filters,
+				policeFilter(rep, netlink.HANDLE_MIN_EGRESS, bw.IngressRate, bw.IngressBurst, conform))
+		}
+	}
+	if len(conf.CTags) > 0 {
+		for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+			filters = append(filters, cTagFilters(rep, parent, conf.CTags)...)
+		}
+	}
+	return filters
+}
+
+// clsactQdisc returns clsact qdisc for the link, filters for ingress and egress traffic are attached to it
+func clsactQdisc(link netlink.Link) *netlink.GenericQdisc {
+	return &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+}
+
+// policeFilter returns matchall filter which drops traffic exceeding the rate,
+// rate is in bits per second and burst is in bits
+func policeFilter(link netlink.Link, parent uint32, rate, burst uint64, conform netlink.TcPolAct) *netlink.MatchAll {
+	police := netlink.NewPoliceAction()
+	police.Rate = uint32(rate / 8)
+	police.Burst = uint32(burst / 8)
+	police.ExceedAction = netlink.TC_POLICE_SHOT
+	police.NotExceedAction = conform
+	return &netlink.MatchAll{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Handle:    bandwidthFilterHandle,
+			Priority:  bandwidthFilterPrio,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Actions: []netlink.Action{police},
+	}
+}
+
+// cTagFilters returns flower filters which pass 802.1Q frames with the C-tags and
+// matchall filter which drops other 802.1Q frames, untagged frames are not filtered
+func cTagFilters(link netlink.Link, parent uint32, ctags []int) []netlink.Filter {
+	filters := make([]netlink.Filter, 0, len(ctags)+1)
+	for i, ctag := range ctags {
+		filters = append(filters, &vlanFlower{
+			Flower: &netlink.Flower{
+				FilterAttrs: netlink.FilterAttrs{
+					LinkIndex: link.Attrs().Index,
+					Parent:    parent,
+					Handle:    uint32(i + 1),
+					Priority:  cTagFilterPrio,
+					Protocol:  unix.ETH_P_8021Q,
+				},
+				Actions: []netlink.Action{gactAction(netlink.TC_ACT_OK)},
+			},
+			vlanID: uint16(ctag),
+		})
+	}
+	filters = append(filters, &netlink.MatchAll{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Handle:    cTagDropFilterHandle,
+			Priority:  cTagDropFilterPrio,
+			Protocol:  unix.ETH_P_8021Q,
+		},
+		Actions: []netlink.Action{gactAction(netlink.TC_ACT_SHOT)},
+	})
+	return filters
+}
+
+// vlanFlower is a flower filter which matches VLAN ID of 802.1Q frames
+type vlanFlower struct {
+	*netlink.Flower
+	vlanID uint16
+}
+
+// gactAction returns generic action with the verdict
+func gactAction(verdict netlink.TcAct) *netlink.GenericAction {
+	return &netlink.GenericAction{ActionAttrs: netlink.ActionAttrs{Action: verdict}}
+}
+
+func (m *manager) addUplinkVlans(conf *types.PluginConf) error {
+	uplink, err := m.getUplink(conf)
+	if err != nil {
+		return err
+	}
+
+	if conf.VxlanDevice != "" {
+		if conf.VniFilter, err = m.checkVxlanVniFilter(uplink); err != nil {
+			return err
+		}
+	}
+
+	vlans := uplinkVlans(conf)
+
+	err = m.vlanUplinkLock.Lock()
+	if err != nil {
+		return fmt.Errorf("failed to create uplink VLAN removal file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+	defer func() {
+		_ = m.vlanUplinkLock.Unlock()
+	}()
+
+	var ledger types.UplinkVlanLedger
+	if ledger, err = m.vlanLedger.Load(); err != nil {
+		return err
+	}
+
+	if vlans, err = m.filterAdminUplinkVlans(ledger, uplink, vlans); err != nil {
+		return err
+	}
+	if len(vlans) == 0 {
+		return nil
+	}
 
+	// record VLANs before adding them to the uplink, owners added by this attachment
+	// are rolled back if adding fails
+	var owned []int
 	for _, vlan := range vlans {
-		found := false
-
-	foundvlan:
-		for _, brlink := range brif {
-			for _, bvlaninfo := range allbrif[int32(brlink.Attrs().Index)] {
-				if bvlaninfo.Vid == uint16(vlan) {
-					found = true
-					break foundvlan
+		if ledgerAddOwner(ledger, uplink.Attrs().Name, vlan, conf.AttachmentID) {
+			owned = append(owned, vlan)
+		}
+	}
+	if err = m.vlanLedger.Save(ledger); err != nil {
+		return err
+	}
+
+	if err = m.configureUplinkVlans(conf, uplink, vlans); err != nil {
+		m.rollbackUplinkVlans(conf, uplink, ledger, owned)
+		return err
+	}
+
+	if conf.VniFilter {
+		if err = m.addVniFilters(conf, uplink, vlans); err != nil {
+			m.rollbackUplinkVlans(conf, uplink, ledger, owned)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configureUplinkVlans adds VLANs to the uplink and VLAN to VNI mappings to the VXLAN device
+func (m *manager) configureUplinkVlans(conf *types.PluginConf, uplink netlink.Link, vlans []int) error {
+	log.Info().Msgf("Setting VLANs for uplink %s: %v", uplink.Attrs().Name, vlans)
+	if err := utils.BridgeTrunkVlanAdd(m.nLink, uplink, vlans); err != nil {
+		return fmt.Errorf("failed to add VLANs to interface %s: %v - %v", uplink.Attrs().Name, vlans, err)
+	}
+
+	if conf.VxlanDevice != "" {
+		return m.addVlanTunnels(conf, uplink, vlans)
+	}
+	return nil
+}
+
+// rollbackUplinkVlans removes owners added by the attachment from the ledger and deletes
+// VLANs and VNI mappings which have no owners left, errors are logged only
+func (m *manager) rollbackUplinkVlans(conf *types.PluginConf, uplink netlink.Link,
+	ledger types.UplinkVlanLedger, owned []int) {
+	var delvlans []int
+	for _, vlan := range owned {
+		if ledgerRemoveOwner(ledger, uplink.Attrs().Name, vlan, conf.AttachmentID) {
+			delvlans = append(delvlans, vlan)
+		}
+	}
+
+	if len(delvlans) > 0 {
+		log.Info().Msgf("Rolling back VLANs for uplink %s: %v", uplink.Attrs().Name, delvlans)
+		if conf.VxlanDevice != "" {
+			if err := m.deleteVlanTunnels(conf, uplink, delvlans); err != nil {
+				log.Warn().Msgf("Failed to roll back VNI mappings on %s: %v", uplink.Attrs().Name, err)
+			}
+		}
+		// VLANs are removed one by one, VLANs which were not added yet can't be removed
+		for _, vlan := range delvlans {
+			if err := utils.BridgeTrunkVlanDel(m.nLink, uplink, []int{vlan}); err != nil {
+				log.Warn().Msgf("Failed to roll back VLAN %d on %s: %v", vlan, uplink.Attrs().Name, err)
+			}
+		}
+	}
+
+	if err := m.vlanLedger.Save(ledger); err != nil {
+		log.Warn().Msgf("Failed to roll back uplink VLAN ledger: %v", err)
+	}
+}
+
+// filterAdminUplinkVlans returns VLANs which are owned by the plugin: VLANs which are recorded in the ledger
+// and VLANs which are not configured on the uplink yet. VLANs configured on the uplink by admin are skipped
+func (m *manager) filterAdminUplinkVlans(ledger types.UplinkVlanLedger, uplink netlink.Link,
+	vlans []int) ([]int, error) {
+	var uplinkVlans []*nl.BridgeVlanInfo
+	listed := false
+	owned := make([]int, 0, len(vlans))
+	for _, vlan := range vlans {
+		if len(ledger[uplink.Attrs().Name][vlan]) == 0 {
+			if !listed {
+				allVlans, err := utils.BridgeVlanList(m.nLink)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list bridge VLANs: %v", err)
 				}
+				uplinkVlans = allVlans[int32(uplink.Attrs().Index)]
+				listed = true
+			}
+			if hasBridgeVlan(uplinkVlans, vlan) {
+				log.Debug().Msgf("VLAN %d is configured on uplink %s not by the plugin, skip it",
+					vlan, uplink.Attrs().Name)
+				continue
+			}
+		}
+		owned = append(owned, vlan)
+	}
+	return owned, nil
+}
+
+func hasBridgeVlan(vlanInfos []*nl.BridgeVlanInfo, vlan int) bool {
+	for _, info := range vlanInfos {
+		if info.Vid == uint16(vlan) {
+			return true
+		}
+	}
+	return false
+}
+
+// getUplink returns the bridge uplink used for VLAN configuration:
+// VXLAN device if it is configured, otherwise PF or a bond the PF is part of
+func (m *manager) getUplink(conf *types.PluginConf) (netlink.Link, error) {
+	if conf.VxlanDevice != "" {
+		uplink, err := m.nLink.LinkByName(conf.VxlanDevice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup VXLAN device %s: %v", conf.VxlanDevice, err)
+		}
+		return uplink, nil
+	}
+
+	uplink, err := m.nLink.LinkByName(conf.PFName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup PF %s: %v", conf.PFName, err)
+	}
+
+	if bonduplink, bonderr := utils.GetParentBondForLink(m.nLink, uplink); bonderr == nil {
+		log.Debug().Msgf("Using bond master as uplink: pf:%s - master:%s",
+			uplink.Attrs().Name, bonduplink.Attrs().Name)
+		uplink = bonduplink
+	}
+	return uplink, nil
+}
+
+// checkVxlanVniFilter returns true if VXLAN device has VNI filtering enabled, VNI filter entries
+// have to be added for mapped VNIs on such device, otherwise the device drops the traffic
+func (m *manager) checkVxlanVniFilter(vxlan netlink.Link) (bool, error) {
+	vniFilter, err := m.nLink.LinkVxlanVniFilter(vxlan)
+	if err != nil {
+		return false, fmt.Errorf("failed to check VNI filtering on VXLAN device %s: %v", vxlan.Attrs().Name, err)
+	}
+	return vniFilter, nil
+}
+
+// addVlanTunnels enables VLAN tunnel mode on VXLAN port and adds VLAN to VNI mappings for the VLANs
+func (m *manager) addVlanTunnels(conf *types.PluginConf, vxlan netlink.Link, vlans []int) error {
+	if err := utils.SetBridgePortFlag(vxlan.Attrs().Name, portFlagVlanTunnel, true); err != nil {
+		return err
+	}
+	for _, entry := range conf.VniMap {
+		if !containsVlan(vlans, entry.Vlan) {
+			continue
+		}
+		log.Info().Msgf("Mapping VLAN %d to VNI %d on %s", entry.Vlan, entry.Vni, vxlan.Attrs().Name)
+		if err := m.nLink.BridgeVlanTunnelAdd(vxlan, uint16(entry.Vlan), entry.Vni); err != nil {
+			return fmt.Errorf("failed to map VLAN %d to VNI %d on %s: %v",
+				entry.Vlan, entry.Vni, vxlan.Attrs().Name, err)
+		}
+	}
+	return nil
+}
+
+// deleteVlanTunnels removes VLAN to VNI mappings for the VLANs from VXLAN port
+func (m *manager) deleteVlanTunnels(conf *types.PluginConf, vxlan netlink.Link, vlans []int) error {
+	for _, entry := range conf.VniMap {
+		if !containsVlan(vlans, entry.Vlan) {
+			continue
+		}
+		log.Info().Msgf("Removing VLAN %d to VNI %d mapping from %s", entry.Vlan, entry.Vni, vxlan.Attrs().Name)
+		if err := m.nLink.BridgeVlanTunnelDel(vxlan, uint16(entry.Vlan), entry.Vni); err != nil {
+			return fmt.Errorf("failed to remove VLAN %d to VNI %d mapping from %s: %v",
+				entry.Vlan, entry.Vni, vxlan.Attrs().Name, err)
+		}
+	}
+	return nil
+}
+
+// addVniFilters adds VNI filter entries for VNIs mapped to the VLANs to VXLAN device with VNI filtering and
+// records the attachment as owner of the VNIs in the VNI ledger. VNIs which are on the device and are not
+// in the ledger are considered to be added by admin and are skipped. Should be called while vlanUplinkLock is held
+func (m *manager) addVniFilters(conf *types.PluginConf, vxlan netlink.Link, vlans []int) (err error) {
+	ledger, err := m.vniLedger.Load()
+	if err != nil {
+		return err
+	}
+	deviceVnis, err := m.nLink.VxlanVniList(vxlan)
+	if err != nil {
+		return fmt.Errorf("failed to list VNI filter entries of %s: %v", vxlan.Attrs().Name, err)
+	}
+
+	name := vxlan.Attrs().Name
+	var owned []int
+	defer func() {
+		if err != nil {
+			m.rollbackVniFilters(conf, vxlan, ledger, owned)
+		}
+	}()
+	for _, entry := range conf.VniMap {
+		vni := int(entry.Vni)
+		if !containsVlan(vlans, entry.Vlan) {
+			continue
+		}
+		if len(ledger[name][vni]) == 0 {
+			if containsVni(deviceVnis, entry.Vni) {
+				log.Debug().Msgf("VNI %d is configured on %s not by the plugin, skip it", entry.Vni, name)
+				continue
+			}
+			log.Info().Msgf("Adding VNI filter entry %d to %s", entry.Vni, name)
+			if err = m.nLink.VxlanVniAdd(vxlan, entry.Vni); err != nil {
+				return fmt.Errorf("failed to add VNI filter entry %d to %s: %v", entry.Vni, name, err)
 			}
 		}
-		if !found {
-			unusedVlans = append(unusedVlans, vlan)
+		if ledgerAddOwner(ledger, name, vni, conf.AttachmentID) {
+			owned = append(owned, vni)
+		}
+	}
+	return m.vniLedger.Save(ledger)
+}
+
+// rollbackVniFilters removes owners added by the attachment from the VNI ledger and deletes
+// VNI filter entries which have no owners left, errors are logged only
+func (m *manager) rollbackVniFilters(conf *types.PluginConf, vxlan netlink.Link,
+	ledger types.UplinkVlanLedger, owned []int) {
+	for _, vni := range owned {
+		if !ledgerRemoveOwner(ledger, vxlan.Attrs().Name, vni, conf.AttachmentID) {
+			continue
 		}
+		if err := m.nLink.VxlanVniDel(vxlan, uint32(vni)); err != nil {
+			log.Warn().Msgf("Failed to roll back VNI filter entry %d on %s: %v", vni, vxlan.Attrs().Name, err)
+		}
+	}
+	if err := m.vniLedger.Save(ledger); err != nil {
+		log.Warn().Msgf("Failed to roll back VNI ledger: %v", err)
+	}
+}
+
+// deleteVniFilters removes the attachment from owners of its VNIs in the VNI ledger and deletes
+// VNI filter entries which have no owners left. Should be called while vlanUplinkLock is held
+func (m *manager) deleteVniFilters(conf *types.PluginConf, vxlan netlink.Link) error {
+	ledger, err := m.vniLedger.Load()
+	if err != nil {
+		return err
+	}
+	for _, entry := range conf.VniMap {
+		if !ledgerRemoveOwner(ledger, vxlan.Attrs().Name, int(entry.Vni), conf.AttachmentID) {
+			continue
+		}
+		log.Info().Msgf("Removing VNI filter entry %d from %s", entry.Vni, vxlan.Attrs().Name)
+		if err = m.nLink.VxlanVniDel(vxlan, entry.Vni); err != nil {
+			return fmt.Errorf("failed to remove VNI filter entry %d from %s: %v", entry.Vni, vxlan.Attrs().Name, err)
+		}
+	}
+	return m.vniLedger.Save(ledger)
+}
+
+func containsVni(vnis []uint32, vni uint32) bool {
+	for _, v := range vnis {
+		if v == vni {
+			return true
+		}
+	}
+	return false
+}
+
+// uplinkVlans returns VLANs of the attachment which are configured on the uplink
+func uplinkVlans(conf *types.PluginConf) []int {
+	vlans := append([]int(nil), conf.Trunk...)
+	if conf.Vlan > 0 {
+		vlans = append(vlans, conf.Vlan)
+	}
+	return vlans
+}
+
+func containsVlan(vlans []int, vlan int) bool {
+	for _, v := range vlans {
+		if v == vlan {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *manager) DetachRepresentor(conf *types.PluginConf) error {
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor %s link: %v", conf.Representor, err)
+	}
+
+	if err = m.nLink.LinkSetDown(rep); err != nil {
+		return fmt.Errorf("failed to set representor %s down: %v", conf.Representor, err)
+	}
+
+	if hasRepresentorFilters(conf) {
+		m.deleteRepresentorFilters(conf, rep)
+	}
+
+	// Restore MTU
+	if conf.MTU != 0 {
+		if err = m.nLink.LinkSetMTU(rep, conf.OrigRepState.MTU); err != nil {
+			return fmt.Errorf("failed to set MTU on rep %s: %v", conf.Representor, err)
+		}
+		log.Info().Msgf("Restoring MTU %d on rep %s", conf.OrigRepState.MTU, conf.Representor)
+	}
+
+	if conf.FdbMAC != "" {
+		if fdbErr := m.deleteStaticFdb(conf, rep); fdbErr != nil {
+			log.Warn().Msgf("Failed to delete static FDB entries for rep %s: %v", conf.Representor, fdbErr)
+		}
+	}
+
+	if len(conf.OrigRepState.PortFlags) > 0 {
+		if flagsErr := restorePortFlags(conf); flagsErr != nil {
+			log.Warn().Msgf("Failed to restore bridge port flags on rep %s: %v", conf.Representor, flagsErr)
+		}
+	}
+
+	log.Info().Msgf("Detaching rep %s from the bridge %s", conf.Representor, conf.ActualBridge)
+
+	if err = m.nLink.LinkSetNoMaster(rep); err != nil {
+		return fmt.Errorf("failed to detatch representor %s from bridge: %v", conf.Representor, err)
+	}
+
+	if conf.SetUplinkVlan || conf.VxlanDevice != "" {
+		if err = m.deleteUplinkVlans(conf); err != nil {
+			log.Warn().Msgf("Failed to delete trunk VLANs from uplink %v", err)
+		}
+	}
+
+	return nil
+}
+
+// AddStaticFdb disables learning on representor port and adds static FDB entries for the MAC
+// for each VLAN configured for the representor
+func (m *manager) AddStaticFdb(conf *types.PluginConf, mac string) error {
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+	if bytes.Equal(hwaddr, make(net.HardwareAddr, len(hwaddr))) {
+		return fmt.Errorf("VF MAC address is not set")
+	}
+
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+
+	if _, saved := conf.OrigRepState.PortFlags[portFlagLearning]; !saved {
+		var orig bool
+		orig, err = utils.GetBridgePortFlag(conf.Representor, portFlagLearning)
+		if err != nil {
+			return err
+		}
+		if conf.OrigRepState.PortFlags == nil {
+			conf.OrigRepState.PortFlags = make(map[string]bool)
+		}
+		conf.OrigRepState.PortFlags[portFlagLearning] = orig
+	}
+	if err = utils.SetBridgePortFlag(conf.Representor, portFlagLearning, false); err != nil {
+		return err
+	}
+
+	// entries are removed by DetachRepresentor
+	conf.FdbMAC = mac
+	for _, vlan := range fdbVlans(conf) {
+		log.Info().Msgf("Adding static FDB entry %s vlan %d for rep %s", mac, vlan, conf.Representor)
+		if err = m.nLink.NeighAdd(fdbEntry(rep, hwaddr, vlan)); err != nil {
+			return fmt.Errorf("failed to add static FDB entry %s vlan %d for representor %s: %v",
+				mac, vlan, conf.Representor, err)
+		}
+	}
+	return nil
+}
+
+// deleteStaticFdb removes static FDB entries added by AddStaticFdb
+func (m *manager) deleteStaticFdb(conf *types.PluginConf, rep netlink.Link) error {
+	hwaddr, err := net.ParseMAC(conf.FdbMAC)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", conf.FdbMAC, err)
+	}
+	for _, vlan := range fdbVlans(conf) {
+		if err = m.nLink.NeighDel(fdbEntry(rep, hwaddr, vlan)); err != nil {
+			return fmt.Errorf("failed to delete static FDB entry %s vlan %d: %v", conf.FdbMAC, vlan, err)
+		}
+	}
+	return nil
+}
+
+// fdbVlans returns list of VLANs for static FDB entries,
+// VLAN 0 means that entry is added without VLAN
+func fdbVlans(conf *types.PluginConf) []int {
+	var vlans []int
+	if conf.Vlan > 0 {
+		vlans = append(vlans, conf.Vlan)
+	}
+	for _, vlan := range conf.Trunk {
+		if vlan != conf.Vlan {
+			vlans = append(vlans, vlan)
+		}
+	}
+	if len(vlans) == 0 {
+		vlans = append(vlans, 0)
+	}
+	return vlans
+}
+
+// FindFdbMACConflicts returns FDB entries of other ports of the bridge which have the VF MAC
+// on VLANs of the attachment, entries on any VLAN are returned for the attachment without VLANs
+func (m *manager) FindFdbMACConflicts(conf *types.PluginConf) ([]string, error) {
+	hwaddr, err := net.ParseMAC(conf.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MAC address %s: %v", conf.MAC, err)
+	}
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+	entries, err := m.nLink.NeighList(0, unix.AF_BRIDGE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list FDB entries: %v", err)
+	}
+
+	anyVlan := conf.Vlan == 0 && len(conf.Trunk) == 0
+	vlans := make(map[int]bool)
+	for _, vlan := range fdbVlans(conf) {
+		vlans[vlan] = true
+	}
+	bridgeIndex := bridge.Attrs().Index
+
+	var conflicts []string
+	for i := range entries {
+		entry := &entries[i]
+		if entry.MasterIndex != bridgeIndex && entry.LinkIndex != bridgeIndex {
+			continue
+		}
+		if entry.LinkIndex == rep.Attrs().Index || !bytes.Equal(entry.HardwareAddr, hwaddr) {
+			continue
+		}
+		if !anyVlan && !vlans[entry.Vlan] {
+			continue
+		}
+		port := strconv.Itoa(entry.LinkIndex)
+		if link, linkErr := m.nLink.LinkByIndex(entry.LinkIndex); linkErr == nil {
+			port = link.Attrs().Name
+		}
+		conflicts = append(conflicts, fmt.Sprintf("FDB entry on port %s vlan %d", port, entry.Vlan))
+	}
+	return conflicts, nil
+}
+
+// fdbEntry returns static master FDB entry for the link
+func fdbEntry(link netlink.Link, hwaddr net.HardwareAddr, vlan int) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       unix.AF_BRIDGE,
+		Flags:        netlink.NTF_MASTER,
+		State:        netlink.NUD_NOARP,
+		HardwareAddr: hwaddr,
+		Vlan:         vlan,
+	}
+}
+
+// GetHostInterfaces returns VF representor and the bridge as host-side interfaces for the CNI result
+func (m *manager) GetHostInterfaces(conf *types.PluginConf) ([]*current.Interface, error) {
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+
+	return []*current.Interface{
+		{Name: rep.Attrs().Name, Mac: rep.Attrs().HardwareAddr.String()},
+		{Name: bridge.Attrs().Name, Mac: bridge.Attrs().HardwareAddr.String()},
+	}, nil
+}
+
+// CheckRepresentor validates that representor is attached to the bridge and has expected configuration
+func (m *manager) CheckRepresentor(conf *types.PluginConf) error {
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+
+	if rep.Attrs().MasterIndex != bridge.Attrs().Index {
+		return fmt.Errorf("representor %s is not attached to the bridge %s", conf.Representor, conf.ActualBridge)
+	}
+
+	if rep.Attrs().Flags&net.FlagUp == 0 {
+		return fmt.Errorf("representor %s is down", conf.Representor)
+	}
+
+	if conf.MTU != 0 && rep.Attrs().MTU != conf.MTU {
+		return fmt.Errorf("representor %s has MTU %d, expected %d", conf.Representor, rep.Attrs().MTU, conf.MTU)
+	}
+
+	return m.checkRepresentorVlans(conf, rep)
+}
+
+// checkRepresentorVlans validates that PVID and trunk VLANs of the representor match configuration
+func (m *manager) checkRepresentorVlans(conf *types.PluginConf, rep netlink.Link) error {
+	// representor uses default bridge VLAN config, nothing to check
+	if conf.Vlan == 0 && len(conf.Trunk) == 0 {
+		return nil
+	}
+
+	allbrif, err := utils.BridgeVlanList(m.nLink)
+	if err != nil {
+		return fmt.Errorf("failed to list bridge VLANs: %v", err)
+	}
+
+	pvid := 0
+	actualTrunk := make(map[int]bool)
+	for _, bvlaninfo := range allbrif[int32(rep.Attrs().Index)] {
+		if bvlaninfo.PortVID() {
+			pvid = int(bvlaninfo.Vid)
+			continue
+		}
+		actualTrunk[int(bvlaninfo.Vid)] = true
+	}
+
+	if pvid != conf.Vlan {
+		return fmt.Errorf("representor %s has PVID VLAN %d, expected %d", conf.Representor, pvid, conf.Vlan)
+	}
+
+	var missing []int
+	expectedTrunk := make(map[int]bool, len(conf.Trunk))
+	for _, vlan := range conf.Trunk {
+		// VLAN from vlan option overrides the same VLAN from trunk
+		if vlan == conf.Vlan {
+			continue
+		}
+		expectedTrunk[vlan] = true
+		if !actualTrunk[vlan] {
+			missing = append(missing, vlan)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("representor %s is missing trunk VLANs %v", conf.Representor, missing)
+	}
+
+	var unexpected []int
+	for vlan := range actualTrunk {
+		if !expectedTrunk[vlan] {
+			unexpected = append(unexpected, vlan)
+		}
+	}
+	if len(unexpected) > 0 {
+		sort.Ints(unexpected)
+		return fmt.Errorf("representor %s has unexpected trunk VLANs %v", conf.Representor, unexpected)
+	}
+
+	return nil
+}
+
+// CheckStatus validates that node is ready to attach representors to the bridges
+func (m *manager) CheckStatus(bridges []string) error {
+	for _, brName := range bridges {
+		bridge, err := m.nLink.LinkByName(brName)
+		if err != nil {
+			return fmt.Errorf("failed to get bridge link %s: %v", brName, err)
+		}
+		if bridge.Type() != "bridge" {
+			return fmt.Errorf("link %s is not a bridge", brName)
+		}
+	}
+
+	// taking the lock ensures that lock directory exist and writable
+	if err := m.vlanUplinkLock.Lock(); err != nil {
+		return fmt.Errorf("failed to take uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+	if err := m.vlanUplinkLock.Unlock(); err != nil {
+		return fmt.Errorf("failed to release uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+
+	return nil
+}
+
+func (m *manager) deleteUplinkVlans(conf *types.PluginConf) error {
+	uplink, err := m.getUplink(conf)
+	if err != nil {
+		return err
+	}
+
+	vlans := uplinkVlans(conf)
+
+	err = m.vlanUplinkLock.Lock()
+	if err != nil {
+		return fmt.Errorf("failed to create uplink VLAN file lock: %s, %v", vlanUplinkLockFile, err)
+	}
+	defer func() {
+		_ = m.vlanUplinkLock.Unlock()
+	}()
+
+	var ledger types.UplinkVlanLedger
+	if ledger, err = m.vlanLedger.Load(); err != nil {
+		return err
+	}
+
+	if conf.VniFilter {
+		if err = m.deleteVniFilters(conf, uplink); err != nil {
+			return err
+		}
+	}
+
+	// remove only VLANs added by the plugin which are not used by other attachments
+	var delvlans []int
+	for _, vlan := range vlans {
+		if ledgerRemoveOwner(ledger, uplink.Attrs().Name, vlan, conf.AttachmentID) {
+			delvlans = append(delvlans, vlan)
+		}
+	}
+	if len(delvlans) == 0 {
+		return m.vlanLedger.Save(ledger)
+	}
+
+	if conf.VxlanDevice != "" {
+		if err = m.deleteVlanTunnels(conf, uplink, delvlans); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msgf("Deleting VLANs for uplink %s: %v", uplink.Attrs().Name, delvlans)
+	if err = utils.BridgeTrunkVlanDel(m.nLink, uplink, delvlans); err != nil {
+		return fmt.Errorf("failed to delete VLANs from interface %s: %v - %v", uplink.Attrs().Name, delvlans, err)
 	}
 
-	return unusedVlans
+	return m.vlanLedger.Save(ledger)
 }
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net"
 	"os"
+	"path/filepath"
 
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,12 +14,16 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 
 	mgrMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager/mocks"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
 	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
 )
 
+const testAttachmentID = "mynet-a1b2c3d4e5f6-net1"
+
 // Fake NS - implements ns.NetNS interface
 type fakeNetNS struct {
 	closed bool
@@ -237,6 +243,54 @@ var _ = Describe("Manager", func() {
 			Expect(netconf.OrigVfState.MTU).To(Equal(origMTU))
 			mocked.AssertExpectations(t)
 		})
+		It("Setting sysctls", func() {
+			targetNetNS := newFakeNs()
+			mocked := &utilsMocks.Netlink{}
+			mockedSysctl := &utilsMocks.Sysctl{}
+			netconf.Sysctl = map[string]string{
+				"net.ipv6.conf.IFNAME.accept_ra": "0",
+				"net.ipv4.conf.net1.arp_notify":  "1",
+			}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			var keys []string
+			mockedSysctl.On("SetInterfaceSysctl", podifName, mock.Anything, mock.Anything).Run(
+				func(args mock.Arguments) {
+					keys = append(keys, args.String(1))
+				}).Return(nil)
+			m := manager{nLink: mocked, sysctl: mockedSysctl}
+			_, err := m.SetupVF(netconf, podifName, contID, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedSysctl.AssertCalled(t, "SetInterfaceSysctl", podifName, "net.ipv6.conf.IFNAME.accept_ra", "0")
+			mockedSysctl.AssertCalled(t, "SetInterfaceSysctl", podifName, "net.ipv4.conf.net1.arp_notify", "1")
+			// sysctls are set in sorted order of the keys
+			Expect(keys).To(Equal([]string{"net.ipv4.conf.net1.arp_notify", "net.ipv6.conf.IFNAME.accept_ra"}))
+		})
+		It("Setting sysctl failed (failure)", func() {
+			targetNetNS := newFakeNs()
+			mocked := &utilsMocks.Netlink{}
+			mockedSysctl := &utilsMocks.Sysctl{}
+			netconf.Sysctl = map[string]string{"net.ipv4.conf.eth0.rp_filter": "0"}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedSysctl.On("SetInterfaceSysctl", podifName, "net.ipv4.conf.eth0.rp_filter", "0").Return(
+				errors.New("some error"))
+			m := manager{nLink: mocked, sysctl: mockedSysctl}
+			_, err := m.SetupVF(netconf, podifName, contID, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetUp", fakeLink)
+			mockedSysctl.AssertExpectations(t)
+		})
 	})
 
 	Context("Checking ReleaseVF function", func() {
@@ -384,6 +438,7 @@ var _ = Describe("Manager", func() {
 			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
 			origMac, err := net.ParseMAC(netconf.OrigVfState.AdminMAC)
 			Expect(err).NotTo(HaveOccurred())
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{}, nil)
 			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, origMac).Return(nil)
 
 			m := manager{nLink: mocked}
@@ -394,7 +449,8 @@ var _ = Describe("Manager", func() {
 	})
 	Context("Checking AttachRepresentor function", func() {
 		var (
-			netconf *types.PluginConf
+			netconf       *types.PluginConf
+			vlanFiltering = true
 		)
 
 		BeforeEach(func() {
@@ -404,6 +460,7 @@ var _ = Describe("Manager", func() {
 					Vlan:     100,
 				},
 				Representor:  "dummylink",
+				AttachmentID: testAttachmentID,
 				PFName:       "enp175s0f1",
 				ActualBridge: "bridge1",
 				VFID:         0,
@@ -418,7 +475,7 @@ var _ = Describe("Manager", func() {
 			netconf.MTU = newMtu
 			mockedNl := &utilsMocks.Netlink{}
 			mockedSr := &utilsMocks.Sriovnet{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}, VlanFiltering: &vlanFiltering}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
 				MasterIndex: 0,
@@ -451,7 +508,7 @@ var _ = Describe("Manager", func() {
 		It("Attaching dummy link to the bridge (failure)", func() {
 			mockedNl := &utilsMocks.Netlink{}
 			mockedSr := &utilsMocks.Sriovnet{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "cni0"}}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "cni0"}, VlanFiltering: &vlanFiltering}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
 				MasterIndex: 0,
@@ -477,7 +534,8 @@ var _ = Describe("Manager", func() {
 			mockedNl := &utilsMocks.Netlink{}
 			mockedSr := &utilsMocks.Sriovnet{}
 			mockedLock := &mgrMocks.IPCLock{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}, VlanFiltering: &vlanFiltering}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
 				MasterIndex: 0,
@@ -509,18 +567,95 @@ var _ = Describe("Manager", func() {
 			// link is not part of a bond
 			mockedNl.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
 			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"enp175s0f1": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {testAttachmentID}}}).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(100), false, false, false, true).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(4), false, false, false, true).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(6), false, false, false, true).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeUpLink.Attrs().MasterIndex).To(Equal(fakeBridge.Attrs().Index))
 			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
 			mockedSr.AssertExpectations(t)
 		})
+		It("Attaching representor skips uplink VLANs configured by admin (success)", func() {
+			netconf.SetUplinkVlan = true
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}, VlanFiltering: &vlanFiltering}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeLink, uint16(1), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(100), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(6), false, false, false, true).Return(nil)
+
+			// addUplinkVlans function
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
+			mockedNl.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
+			mockedLock.On("Lock").Return(nil)
+			// VLAN 100 was added by other attachment, VLAN 4 was configured by admin
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"enp175s0f1": {100: {"other"}}}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				20: {{Flags: 0, Vid: 4}, {Flags: 0, Vid: 100}}}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"enp175s0f1": {6: {testAttachmentID}, 100: {"other", testAttachmentID}}}).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(100), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(6), false, false, false, true).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedNl.AssertNotCalled(t, "BridgeVlanAdd", fakeUpLink, uint16(4), false, false, false, true)
+		})
+		It("Attaching representor rolls back uplink VLANs if adding fails (failure)", func() {
+			netconf.SetUplinkVlan = true
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}, VlanFiltering: &vlanFiltering}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
+			mockedNl.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
+			mockedLock.On("Lock").Return(nil)
+			// VLAN 100 was added by other attachment
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"enp175s0f1": {100: {"other"}}}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				20: {{Flags: 0, Vid: 100}}}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"enp175s0f1": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {"other", testAttachmentID}}}).
+				Return(nil).Once()
+			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(6), false, false, false, true).Return(errors.New("some error"))
+			// only VLANs without other owners are removed, VLAN 6 was not added
+			mockedNl.On("BridgeVlanDel", fakeUpLink, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeUpLink, uint16(6), false, false, false, true).Return(errors.New("some error"))
+			mockedLedger.On("Save", types.UplinkVlanLedger{"enp175s0f1": {100: {"other"}}}).Return(nil).Once()
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.addUplinkVlans(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedNl.AssertNotCalled(t, "BridgeVlanDel", fakeUpLink, uint16(100), false, false, false, true)
+		})
 		It("Attaching dummy link to the bridge setting bond uplink vlans (success)", func() {
 			origMtu := 1500
 			newMtu := 2000
@@ -529,7 +664,8 @@ var _ = Describe("Manager", func() {
 			mockedNl := &utilsMocks.Netlink{}
 			mockedSr := &utilsMocks.Sriovnet{}
 			mockedLock := &mgrMocks.IPCLock{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}, VlanFiltering: &vlanFiltering}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
 				MasterIndex: 0,
@@ -568,18 +704,509 @@ var _ = Describe("Manager", func() {
 			// link is part of a bond
 			mockedNl.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBondUpLink, nil)
 			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"bond0": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {testAttachmentID}}}).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeBondUpLink, uint16(100), false, false, false, true).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeBondUpLink, uint16(4), false, false, false, true).Return(nil)
 			mockedNl.On("BridgeVlanAdd", fakeBondUpLink, uint16(6), false, false, false, true).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeBondUpLink.Attrs().MasterIndex).To(Equal(fakeBridge.Attrs().Index))
 			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("Attaching representor with bridge port flags (success)", func() {
+			netconf.Vlan = 0
+			netconf.Trunk = nil
+			netconf.Representor = "pf0vf0"
+			learning := false
+			hairpin := true
+			netconf.PortFlags = &types.PortFlags{Learning: &learning, Hairpin: &hairpin}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigRepState.PortFlags).To(Equal(map[string]bool{"learning": true, "hairpin_mode": false}))
+			Expect(utils.GetBridgePortFlag("pf0vf0", "learning")).To(BeFalse())
+			Expect(utils.GetBridgePortFlag("pf0vf0", "hairpin_mode")).To(BeTrue())
+			mockedNl.AssertExpectations(t)
+
+			// restore flags for other tests
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(utils.GetBridgePortFlag("pf0vf0", "learning")).To(BeTrue())
+			Expect(utils.GetBridgePortFlag("pf0vf0", "hairpin_mode")).To(BeFalse())
+		})
+		It("Attaching representor with bridge port flags, flag is not supported (failure)", func() {
+			netconf.Vlan = 0
+			netconf.Trunk = nil
+			netconf.Representor = "pf0vf0"
+			isolated := true
+			netconf.PortFlags = &types.PortFlags{Isolated: &isolated}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("VLAN config on the bridge without vlan_filtering (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+
+			m := manager{nLink: mockedNl, sriov: &utilsMocks.Sriovnet{}}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Bridge doesn't exist (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(nil, netlink.LinkNotFoundError{})
+
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Creating the bridge and attaching uplink (success)", func() {
+			netconf.Vlan = 0
+			netconf.Trunk = nil
+			netconf.ActualBridge = "br-new"
+			netconf.CreateBridge = true
+			netconf.BridgeMTU = 9000
+			netconf.BridgeVlanProtocol = "802.1ad"
+			netconf.BridgeAttachUplink = true
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "br-new"},
+				VlanFiltering: &vlanFiltering}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20}}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+
+			mockedNl.On("LinkByName", "br-new").Return(nil, netlink.LinkNotFoundError{}).Once()
+			mockedNl.On("LinkAdd", mock.MatchedBy(func(br *netlink.Bridge) bool {
+				return br.Name == "br-new" && br.MTU == 9000 && br.VlanFiltering != nil && *br.VlanFiltering
+			})).Return(nil)
+			mockedNl.On("LinkByName", "br-new").Return(fakeBridge, nil).Once()
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
+			mockedNl.On("LinkSetMaster", fakeUpLink, fakeBridge).Return(nil)
+			mockedNl.On("LinkSetUp", fakeBridge).Return(nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			proto, err := os.ReadFile(filepath.Join(utils.NetDirectory, "br-new", "bridge", "vlan_protocol"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(proto)).To(Equal("0x88a8"))
+		})
+		It("Creating the bridge, uplink is attached to another bridge (failure)", func() {
+			netconf.ActualBridge = "br-new"
+			netconf.CreateBridge = true
+			netconf.BridgeAttachUplink = true
+			mockedNl := &utilsMocks.Netlink{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "br-new"},
+				VlanFiltering: &vlanFiltering}
+			otherBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 2000, Name: "cni0"}}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20, MasterIndex: 2000}}
+
+			mockedNl.On("LinkByName", "br-new").Return(nil, netlink.LinkNotFoundError{}).Once()
+			mockedNl.On("LinkAdd", mock.Anything).Return(nil)
+			mockedNl.On("LinkByName", "br-new").Return(fakeBridge, nil).Once()
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
+			mockedNl.On("LinkByIndex", 2000).Return(otherBridge, nil)
+
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Attaching representor with 802.1ad service VLAN (success)", func() {
+			netconf.VlanProtocol = "802.1ad"
+			netconf.ActualBridge = "br-qinq"
+			netconf.Trunk = nil
+			netconf.SetUplinkVlan = true
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "br-qinq"},
+				VlanFiltering: &vlanFiltering}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: netconf.PFName, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeLink, uint16(1), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(100), true, true, false, true).Return(nil)
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
+			mockedNl.On("LinkByIndex", 1000).Return(fakeBridge, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"enp175s0f1": {100: {testAttachmentID}}}).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(100), false, false, false, true).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("Attaching representor with 802.1ad service VLAN and C-tags (success)", func() {
+			netconf.VlanProtocol = "802.1ad"
+			netconf.ActualBridge = "br-qinq"
+			netconf.Trunk = nil
+			netconf.CTags = []int{10, 11}
+			netconf.RuntimeConfig.Bandwidth = &types.BandwidthEntry{EgressRate: 16000000, EgressBurst: 160000}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			mockedEt := &utilsMocks.Ethtool{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "br-qinq"},
+				VlanFiltering: &vlanFiltering}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10}}
+			isCTagFilter := func(parent uint32, vid uint16) interface{} {
+				return mock.MatchedBy(func(f *netlink.Flower) bool {
+					action, ok := f.Actions[0].(*netlink.GenericAction)
+					return f.Parent == parent && f.Priority == cTagFilterPrio && f.Protocol == unix.ETH_P_8021Q &&
+						f.Handle == uint32(vid-9) && ok && action.Action == netlink.TC_ACT_OK
+				})
+			}
+			isCTagDropFilter := func(parent uint32) interface{} {
+				return mock.MatchedBy(func(f *netlink.MatchAll) bool {
+					action, ok := f.Actions[0].(*netlink.GenericAction)
+					return f.Parent == parent && f.Priority == cTagDropFilterPrio && f.Protocol == unix.ETH_P_8021Q &&
+						ok && action.Action == netlink.TC_ACT_SHOT
+				})
+			}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			// only S-tag is configured on the bridge
+			mockedNl.On("BridgeVlanDel", fakeLink, uint16(1), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(100), true, true, false, true).Return(nil)
+			mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+			mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+			// conforming traffic is passed to C-tag filters
+			mockedNl.On("MatchAllFilterAdd", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+				police, ok := f.Actions[0].(*netlink.PoliceAction)
+				return f.Priority == bandwidthFilterPrio && ok && police.NotExceedAction == netlink.TC_POLICE_PIPE
+			}), utils.TCClsFlagsSkipSW).Return(nil).Once()
+			for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+				mockedNl.On("VlanFlowerFilterAdd", isCTagFilter(parent, 10), uint16(10), utils.TCClsFlagsSkipSW).
+					Return(nil).Once()
+				mockedNl.On("VlanFlowerFilterAdd", isCTagFilter(parent, 11), uint16(11), utils.TCClsFlagsSkipSW).
+					Return(nil).Once()
+				mockedNl.On("MatchAllFilterAdd", isCTagDropFilter(parent), utils.TCClsFlagsSkipSW).Return(nil).Once()
+			}
+
+			m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedEt.AssertExpectations(t)
+		})
+		It("Attaching representor, bridge VLAN protocol mismatch (failure)", func() {
+			netconf.VlanProtocol = "802.1Q"
+			netconf.ActualBridge = "br-qinq"
+			mockedNl := &utilsMocks.Netlink{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "br-qinq"},
+				VlanFiltering: &vlanFiltering}
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+
+			m := manager{nLink: mockedNl, sriov: &utilsMocks.Sriovnet{}}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Attaching representor and mapping VLANs to VNIs on VXLAN device (success)", func() {
+			netconf.VxlanDevice = "vxlan0"
+			netconf.VniMap = []types.VniMapEntry{{Vlan: 100, Vni: 10100}, {Vlan: 4, Vni: 10004}, {Vlan: 6, Vni: 10006}}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"},
+				VlanFiltering: &vlanFiltering}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan0", Index: 40, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeLink, uint16(1), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(100), true, true, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(6), false, false, false, true).Return(nil)
+
+			// addUplinkVlans function
+			mockedNl.On("LinkByName", "vxlan0").Return(fakeVxlan, nil)
+			mockedNl.On("LinkVxlanVniFilter", fakeVxlan).Return(false, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"vxlan0": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {testAttachmentID}}}).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeVxlan, uint16(100), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeVxlan, uint16(4), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeVxlan, uint16(6), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanTunnelAdd", fakeVxlan, uint16(100), uint32(10100)).Return(nil)
+			mockedNl.On("BridgeVlanTunnelAdd", fakeVxlan, uint16(4), uint32(10004)).Return(nil)
+			mockedNl.On("BridgeVlanTunnelAdd", fakeVxlan, uint16(6), uint32(10006)).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(utils.GetBridgePortFlag("vxlan0", "vlan_tunnel")).To(BeTrue())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
 			mockedSr.AssertExpectations(t)
 		})
+		It("Adding VNI filter entries to VXLAN device with VNI filtering (success)", func() {
+			netconf.VxlanDevice = "vxlan0"
+			netconf.VniMap = []types.VniMapEntry{{Vlan: 100, Vni: 10100}, {Vlan: 4, Vni: 10004}, {Vlan: 6, Vni: 10006}}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			mockedVniLedger := &mgrMocks.VlanLedgerStore{}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan0", Index: 40, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", "vxlan0").Return(fakeVxlan, nil)
+			mockedNl.On("LinkVxlanVniFilter", fakeVxlan).Return(true, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{
+				"vxlan0": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {testAttachmentID}}}).Return(nil)
+			mockedNl.On("BridgeVlanAdd", fakeVxlan, mock.Anything, false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanTunnelAdd", fakeVxlan, mock.Anything, mock.Anything).Return(nil)
+			// VNI 10004 is used by other attachment, VNI 10006 is added by admin
+			mockedVniLedger.On("Load").Return(types.UplinkVlanLedger{"vxlan0": {10004: {"other"}}}, nil)
+			mockedNl.On("VxlanVniList", fakeVxlan).Return([]uint32{10004, 10006}, nil)
+			mockedNl.On("VxlanVniAdd", fakeVxlan, uint32(10100)).Return(nil)
+			mockedVniLedger.On("Save", types.UplinkVlanLedger{
+				"vxlan0": {10004: {"other", testAttachmentID}, 10100: {testAttachmentID}}}).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger,
+				vniLedger: mockedVniLedger}
+			Expect(m.addUplinkVlans(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.VniFilter).To(BeTrue())
+			mockedNl.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mockedVniLedger.AssertExpectations(t)
+		})
+		It("Adding VNI filter entry failed, added entries and VLANs are rolled back (failure)", func() {
+			netconf.VxlanDevice = "vxlan0"
+			netconf.Trunk = nil
+			netconf.VniMap = []types.VniMapEntry{{Vlan: 100, Vni: 10100}}
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			mockedVniLedger := &mgrMocks.VlanLedgerStore{}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan0", Index: 40, MasterIndex: 1000}}
+
+			mockedNl.On("LinkByName", "vxlan0").Return(fakeVxlan, nil)
+			mockedNl.On("LinkVxlanVniFilter", fakeVxlan).Return(true, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{"vxlan0": {100: {testAttachmentID}}}).Return(nil).Once()
+			mockedNl.On("BridgeVlanAdd", fakeVxlan, uint16(100), false, false, false, true).Return(nil)
+			mockedNl.On("BridgeVlanTunnelAdd", fakeVxlan, uint16(100), uint32(10100)).Return(nil)
+			mockedVniLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedNl.On("VxlanVniList", fakeVxlan).Return(nil, nil)
+			mockedNl.On("VxlanVniAdd", fakeVxlan, uint32(10100)).Return(errors.New("some error"))
+			mockedVniLedger.On("Save", types.UplinkVlanLedger{}).Return(nil)
+			// rollbackUplinkVlans function
+			mockedNl.On("BridgeVlanTunnelDel", fakeVxlan, uint16(100), uint32(10100)).Return(nil)
+			mockedNl.On("BridgeVlanDel", fakeVxlan, uint16(100), false, false, false, true).Return(nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{}).Return(nil).Once()
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger,
+				vniLedger: mockedVniLedger}
+			Expect(m.addUplinkVlans(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedNl.AssertNotCalled(t, "VxlanVniDel", mock.Anything, mock.Anything)
+			mockedLedger.AssertExpectations(t)
+			mockedVniLedger.AssertExpectations(t)
+		})
+		Context("with bandwidth limits", func() {
+			var (
+				mockedNl   *utilsMocks.Netlink
+				mockedSr   *utilsMocks.Sriovnet
+				mockedEt   *utilsMocks.Ethtool
+				fakeBridge *netlink.Bridge
+				fakeLink   *FakeLink
+			)
+			BeforeEach(func() {
+				netconf.Vlan = 0
+				netconf.Trunk = nil
+				netconf.RuntimeConfig.Bandwidth = &types.BandwidthEntry{
+					IngressRate: 8000000, IngressBurst: 80000,
+					EgressRate: 16000000, EgressBurst: 160000,
+				}
+				mockedNl = &utilsMocks.Netlink{}
+				mockedSr = &utilsMocks.Sriovnet{}
+				mockedEt = &utilsMocks.Ethtool{}
+				fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+				fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10}}
+
+				mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+				mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+				mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
+				mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+				mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			})
+			isPoliceFilter := func(parent uint32, rate, burst uint32) interface{} {
+				return mock.MatchedBy(func(f *netlink.MatchAll) bool {
+					if f.Parent != parent || f.LinkIndex != 10 || len(f.Actions) != 1 {
+						return false
+					}
+					police, ok := f.Actions[0].(*netlink.PoliceAction)
+					return ok && police.Rate == rate && police.Burst == burst &&
+						police.ExceedAction == netlink.TC_POLICE_SHOT
+				})
+			}
+			It("Offloaded police filters (success)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+				mockedNl.On("MatchAllFilterAdd",
+					isPoliceFilter(netlink.HANDLE_MIN_INGRESS, 2000000, 20000), utils.TCClsFlagsSkipSW).Return(nil)
+				mockedNl.On("MatchAllFilterAdd",
+					isPoliceFilter(netlink.HANDLE_MIN_EGRESS, 1000000, 10000), utils.TCClsFlagsSkipSW).Return(nil)
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+				Expect(netconf.OrigRepState.ClsactAdded).To(BeTrue())
+				mockedNl.AssertExpectations(t)
+				mockedEt.AssertExpectations(t)
+			})
+			It("Police filters are added to existing clsact qdisc (success)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(unix.EEXIST)
+				// admin filter with other priority is kept
+				adminFilter := &netlink.MatchAll{FilterAttrs: netlink.FilterAttrs{LinkIndex: 10, Priority: 10}}
+				mockedNl.On("FilterList", fakeLink, uint32(netlink.HANDLE_MIN_INGRESS)).
+					Return([]netlink.Filter{adminFilter}, nil)
+				mockedNl.On("FilterList", fakeLink, uint32(netlink.HANDLE_MIN_EGRESS)).Return(nil, nil)
+				mockedNl.On("MatchAllFilterAdd", mock.Anything, utils.TCClsFlagsSkipSW).Return(nil).Twice()
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+				Expect(netconf.OrigRepState.ClsactAdded).To(BeFalse())
+				mockedNl.AssertExpectations(t)
+			})
+			It("Failed to add police filter to existing clsact qdisc (failure)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(unix.EEXIST)
+				mockedNl.On("FilterList", fakeLink, mock.Anything).Return(nil, nil)
+				mockedNl.On("MatchAllFilterAdd",
+					isPoliceFilter(netlink.HANDLE_MIN_INGRESS, 2000000, 20000), mock.Anything).Return(nil)
+				mockedNl.On("MatchAllFilterAdd",
+					isPoliceFilter(netlink.HANDLE_MIN_EGRESS, 1000000, 10000), mock.Anything).
+					Return(errors.New("not supported"))
+				// only the filter added by the plugin is removed, the qdisc is kept
+				mockedNl.On("FilterDel", isPoliceFilter(netlink.HANDLE_MIN_INGRESS, 2000000, 20000)).Return(nil)
+				mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+				mockedNl.AssertExpectations(t)
+				mockedNl.AssertNotCalled(t, "QdiscDel", mock.Anything)
+			})
+			It("Existing filter with the same priority (failure)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(unix.EEXIST)
+				adminFilter := &netlink.MatchAll{FilterAttrs: netlink.FilterAttrs{LinkIndex: 10, Priority: 1}}
+				mockedNl.On("FilterList", fakeLink, uint32(netlink.HANDLE_MIN_INGRESS)).
+					Return([]netlink.Filter{adminFilter}, nil)
+				mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				err := m.AttachRepresentor(netconf)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("priority 1 already exists"))
+				mockedNl.AssertExpectations(t)
+				mockedNl.AssertNotCalled(t, "MatchAllFilterAdd", mock.Anything, mock.Anything)
+				mockedNl.AssertNotCalled(t, "FilterDel", mock.Anything)
+				mockedNl.AssertNotCalled(t, "QdiscDel", mock.Anything)
+			})
+			It("Failed to add uplink VLANs after bandwidth limits, limits are removed (failure)", func() {
+				netconf.SetUplinkVlan = true
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+				mockedNl.On("MatchAllFilterAdd", mock.Anything, utils.TCClsFlagsSkipSW).Return(nil).Twice()
+				mockedNl.On("LinkByName", netconf.PFName).Return(nil, errors.New("not found"))
+				mockedNl.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+					return f.Parent == netlink.HANDLE_MIN_INGRESS && f.Handle == bandwidthFilterHandle
+				})).Return(nil).Once()
+				mockedNl.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+					return f.Parent == netlink.HANDLE_MIN_EGRESS && f.Handle == bandwidthFilterHandle
+				})).Return(nil).Once()
+				mockedNl.On("QdiscDel", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil).Once()
+				mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+				mockedNl.AssertExpectations(t)
+			})
+			It("Police filters without offload (success)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": false}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+				mockedNl.On("MatchAllFilterAdd", mock.Anything, uint32(0)).Return(nil).Twice()
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+				mockedNl.AssertExpectations(t)
+			})
+			It("Failed to add police filter (failure)", func() {
+				mockedEt.On("Features", netconf.Representor).Return(map[string]bool{"hw-tc-offload": true}, nil)
+				mockedNl.On("QdiscAdd", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+				mockedNl.On("MatchAllFilterAdd", mock.Anything, mock.Anything).Return(errors.New("not supported"))
+				mockedNl.On("QdiscDel", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil)
+				mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+
+				m := manager{nLink: mockedNl, sriov: mockedSr, ethtool: mockedEt}
+				Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+				mockedNl.AssertExpectations(t)
+			})
+		})
 	})
 	Context("Checking DetachRepresentor function", func() {
 		var (
@@ -594,10 +1221,11 @@ var _ = Describe("Manager", func() {
 					DeviceID: "0000:af:06.0",
 					Vlan:     100,
 				},
-				Representor: "dummylink",
-				PFName:      "enp175s0f1",
-				VFID:        0,
-				MTU:         newMtu,
+				Representor:  "dummylink",
+				AttachmentID: testAttachmentID,
+				PFName:       "enp175s0f1",
+				VFID:         0,
+				MTU:          newMtu,
 				OrigRepState: types.RepState{
 					MTU: origMtu,
 				},
@@ -627,10 +1255,88 @@ var _ = Describe("Manager", func() {
 			Expect(fakeLink.Attrs().MasterIndex).To(Equal(0))
 			mocked.AssertExpectations(t)
 		})
+		It("Detaching dummy link from the bridge and removing bandwidth limits (success)", func() {
+			netconf.RuntimeConfig.Bandwidth = &types.BandwidthEntry{EgressRate: 16000000, EgressBurst: 160000}
+			netconf.OrigRepState.ClsactAdded = true
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{
+				Name:        netconf.Representor,
+				Index:       10,
+				MasterIndex: 1000,
+			}}
+
+			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+				return f.LinkIndex == 10 && f.Parent == netlink.HANDLE_MIN_INGRESS &&
+					f.Priority == bandwidthFilterPrio && f.Handle == bandwidthFilterHandle
+			})).Return(nil)
+			mocked.On("QdiscDel", mock.MatchedBy(func(q *netlink.GenericQdisc) bool {
+				return q.QdiscType == "clsact" && q.LinkIndex == 10
+			})).Return(nil)
+			mocked.On("LinkSetNoMaster", fakeLink).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, origMtu).Return(nil)
+
+			m := manager{nLink: mocked}
+			err := m.DetachRepresentor(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Detaching dummy link from the bridge and removing C-tag filters (success)", func() {
+			netconf.VlanProtocol = "802.1ad"
+			netconf.CTags = []int{10}
+			netconf.OrigRepState.ClsactAdded = true
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10, MasterIndex: 1000}}
+
+			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+				p := parent
+				mocked.On("FilterDel", mock.MatchedBy(func(f *vlanFlower) bool {
+					return f.Parent == p && f.Priority == cTagFilterPrio && f.Handle == 1 && f.vlanID == 10
+				})).Return(nil).Once()
+				mocked.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+					return f.Parent == p && f.Priority == cTagDropFilterPrio && f.Handle == cTagDropFilterHandle
+				})).Return(nil).Once()
+			}
+			mocked.On("QdiscDel", mock.AnythingOfType("*netlink.GenericQdisc")).Return(nil).Once()
+			mocked.On("LinkSetNoMaster", fakeLink).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, origMtu).Return(nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Detaching dummy link from the bridge keeps clsact qdisc not added by the plugin (success)", func() {
+			netconf.RuntimeConfig.Bandwidth = &types.BandwidthEntry{
+				IngressRate: 8000000, IngressBurst: 80000,
+				EgressRate: 16000000, EgressBurst: 160000,
+			}
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10, MasterIndex: 1000}}
+
+			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+				return f.Parent == netlink.HANDLE_MIN_INGRESS && f.Handle == bandwidthFilterHandle
+			})).Return(nil).Once()
+			mocked.On("FilterDel", mock.MatchedBy(func(f *netlink.MatchAll) bool {
+				return f.Parent == netlink.HANDLE_MIN_EGRESS && f.Handle == bandwidthFilterHandle
+			})).Return(nil).Once()
+			mocked.On("LinkSetNoMaster", fakeLink).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, origMtu).Return(nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "QdiscDel", mock.Anything)
+		})
 		It("Detaching dummy link from the bridge and removing uplink vlans (success)", func() {
 			netconf.SetUplinkVlan = true
 			mocked := &utilsMocks.Netlink{}
 			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
 			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
@@ -643,14 +1349,6 @@ var _ = Describe("Manager", func() {
 				MasterIndex: 1000,
 				MTU:         origMtu,
 			}}
-			fakeVlanInfo := map[int32][]*nl.BridgeVlanInfo{
-				10: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-				20: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-			}
 
 			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
 			mocked.On("LinkSetDown", fakeLink).Return(nil)
@@ -665,24 +1363,26 @@ var _ = Describe("Manager", func() {
 			// link is not part of a bond
 			mocked.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
 			mockedLock.On("Lock").Return(nil)
-			mocked.On("LinkList").Return([]netlink.Link{fakeLink, fakeUpLink}, nil)
-			mocked.On("BridgeVlanList").Return(fakeVlanInfo, nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{
+				"enp175s0f1": {4: {testAttachmentID}, 6: {testAttachmentID}, 100: {testAttachmentID}}}, nil)
 			mocked.On("BridgeVlanDel", fakeUpLink, uint16(100), false, false, false, true).Return(nil)
 			mocked.On("BridgeVlanDel", fakeUpLink, uint16(4), false, false, false, true).Return(nil)
 			mocked.On("BridgeVlanDel", fakeUpLink, uint16(6), false, false, false, true).Return(nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{}).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mocked, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
 			err := m.DetachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeLink.Attrs().MasterIndex).To(Equal(0))
 			mocked.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
 		})
-		It("Detaching dummy link from the bridge and removing bond uplink vlans (success)", func() {
+		It("Detaching dummy link from the bridge and removing bond uplink vlans with 2 in use (success)", func() {
 			netconf.SetUplinkVlan = true
 			mocked := &utilsMocks.Netlink{}
 			mockedLock := &mgrMocks.IPCLock{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
 				Index:       10,
@@ -700,14 +1400,6 @@ var _ = Describe("Manager", func() {
 				MasterIndex: 1000,
 				MTU:         origMtu,
 			}}
-			fakeVlanInfo := map[int32][]*nl.BridgeVlanInfo{
-				10: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-				30: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-			}
 
 			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
 			mocked.On("LinkSetDown", fakeLink).Return(nil)
@@ -721,58 +1413,30 @@ var _ = Describe("Manager", func() {
 			mocked.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
 			// link is part of a bond
 			mocked.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBondUpLink, nil)
-			mocked.On("LinkByIndex", fakeBondUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
 			mockedLock.On("Lock").Return(nil)
-			mocked.On("LinkList").Return([]netlink.Link{fakeLink, fakeBondUpLink}, nil)
-			mocked.On("BridgeVlanList").Return(fakeVlanInfo, nil)
-			mocked.On("BridgeVlanDel", fakeBondUpLink, uint16(100), false, false, false, true).Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"bond0": {
+				4:   {testAttachmentID},
+				6:   {"other", testAttachmentID},
+				100: {testAttachmentID, "other"}}}, nil)
 			mocked.On("BridgeVlanDel", fakeBondUpLink, uint16(4), false, false, false, true).Return(nil)
-			mocked.On("BridgeVlanDel", fakeBondUpLink, uint16(6), false, false, false, true).Return(nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{"bond0": {6: {"other"}, 100: {"other"}}}).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mocked, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
 			err := m.DetachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeLink.Attrs().MasterIndex).To(Equal(0))
 			mocked.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
 		})
-		It("Detaching dummy link from the bridge and removing bond uplink vlans with 2 in use (success)", func() {
-			netconf.SetUplinkVlan = true
+		It("Detaching dummy link from the bridge and removing unused VNI mappings (success)", func() {
+			netconf.VxlanDevice = "vxlan0"
+			netconf.VniMap = []types.VniMapEntry{{Vlan: 100, Vni: 10100}, {Vlan: 4, Vni: 10004}, {Vlan: 6, Vni: 10006}}
 			mocked := &utilsMocks.Netlink{}
 			mockedLock := &mgrMocks.IPCLock{}
-			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
-			fakeLink := &FakeLink{netlink.LinkAttrs{
-				Name:        netconf.Representor,
-				Index:       10,
-				MasterIndex: 1000,
-			}}
-			fakeLinkOther := &FakeLink{netlink.LinkAttrs{
-				Name:        netconf.Representor,
-				Index:       15,
-				MasterIndex: 1000,
-			}}
-			fakeUpLink := &FakeLink{netlink.LinkAttrs{
-				Name:        "enp175s0f1",
-				Index:       20,
-				MasterIndex: 30,
-				MTU:         origMtu,
-			}}
-			fakeBondUpLink := &FakeBondLink{netlink.LinkAttrs{
-				Name:        "bond0",
-				Index:       30,
-				MasterIndex: 1000,
-				MTU:         origMtu,
-			}}
-			fakeVlanInfo := map[int32][]*nl.BridgeVlanInfo{
-				10: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-				15: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 6}},
-				30: {{Flags: 0, Vid: 100},
-					{Flags: 0, Vid: 4},
-					{Flags: 0, Vid: 6}},
-			}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10, MasterIndex: 1000}}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan0", Index: 40, MasterIndex: 1000}}
 
 			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
 			mocked.On("LinkSetDown", fakeLink).Return(nil)
@@ -783,48 +1447,93 @@ var _ = Describe("Manager", func() {
 			mocked.On("LinkSetMTU", fakeLink, origMtu).Return(nil)
 
 			// deleteUplinkVlans function
+			mocked.On("LinkByName", "vxlan0").Return(fakeVxlan, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"vxlan0": {
+				4:   {testAttachmentID},
+				6:   {testAttachmentID, "other"},
+				100: {testAttachmentID}}}, nil)
+			mocked.On("BridgeVlanTunnelDel", fakeVxlan, uint16(100), uint32(10100)).Return(nil)
+			mocked.On("BridgeVlanTunnelDel", fakeVxlan, uint16(4), uint32(10004)).Return(nil)
+			mocked.On("BridgeVlanDel", fakeVxlan, uint16(4), false, false, false, true).Return(nil)
+			mocked.On("BridgeVlanDel", fakeVxlan, uint16(100), false, false, false, true).Return(nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{"vxlan0": {6: {"other"}}}).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "BridgeVlanTunnelDel", fakeVxlan, uint16(6), uint32(10006))
+		})
+		It("Deleting VNI filter entries which are not used by other attachments (success)", func() {
+			netconf.VxlanDevice = "vxlan0"
+			netconf.VniFilter = true
+			netconf.Trunk = nil
+			netconf.VniMap = []types.VniMapEntry{{Vlan: 100, Vni: 10100}, {Vlan: 4, Vni: 10004}}
+			mocked := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			mockedVniLedger := &mgrMocks.VlanLedgerStore{}
+			fakeVxlan := &FakeLink{netlink.LinkAttrs{Name: "vxlan0", Index: 40, MasterIndex: 1000}}
+
+			mocked.On("LinkByName", "vxlan0").Return(fakeVxlan, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{"vxlan0": {100: {testAttachmentID, "other"}}}, nil)
+			// VNI 10004 is used by other attachment with another VLAN
+			mockedVniLedger.On("Load").Return(types.UplinkVlanLedger{"vxlan0": {
+				10004: {testAttachmentID, "other"},
+				10100: {testAttachmentID}}}, nil)
+			mocked.On("VxlanVniDel", fakeVxlan, uint32(10100)).Return(nil)
+			mockedVniLedger.On("Save", types.UplinkVlanLedger{"vxlan0": {10004: {"other"}}}).Return(nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{"vxlan0": {100: {"other"}}}).Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger,
+				vniLedger: mockedVniLedger}
+			Expect(m.deleteUplinkVlans(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "VxlanVniDel", fakeVxlan, uint32(10004))
+			mockedLedger.AssertExpectations(t)
+			mockedVniLedger.AssertExpectations(t)
+		})
+		It("Deleting uplink vlans which are not in the ledger (success)", func() {
+			netconf.SetUplinkVlan = true
+			mocked := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20}}
+
 			mocked.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
-			// link is part of a bond
-			mocked.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBondUpLink, nil)
-			mocked.On("LinkByIndex", fakeBondUpLink.Attrs().MasterIndex).Return(fakeBridge, nil)
 			mockedLock.On("Lock").Return(nil)
-			mocked.On("LinkList").Return([]netlink.Link{fakeLink, fakeLinkOther, fakeBondUpLink}, nil)
-			mocked.On("BridgeVlanList").Return(fakeVlanInfo, nil)
-			mocked.On("BridgeVlanDel", fakeBondUpLink, uint16(4), false, false, false, true).Return(nil)
+			// VLANs were configured on the uplink by admin
+			mockedLedger.On("Load").Return(types.UplinkVlanLedger{}, nil)
+			mockedLedger.On("Save", types.UplinkVlanLedger{}).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mocked, vlanUplinkLock: mockedLock}
-			err := m.DetachRepresentor(netconf)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(fakeLink.Attrs().MasterIndex).To(Equal(0))
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.deleteUplinkVlans(netconf)).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "BridgeVlanDel", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything)
 		})
-		It("Deleting uplink vlans for bond not part of a bridge (failure)", func() {
+		It("Deleting uplink vlans, failed to load the ledger (failure)", func() {
 			netconf.SetUplinkVlan = true
 			mocked := &utilsMocks.Netlink{}
-			fakeUpLink := &FakeLink{netlink.LinkAttrs{
-				Name:        "enp175s0f1",
-				Index:       20,
-				MasterIndex: 30,
-				MTU:         origMtu,
-			}}
-			fakeBondUpLink := &FakeBondLink{netlink.LinkAttrs{
-				Name:        "bond0",
-				Index:       30,
-				MasterIndex: 0,
-				MTU:         origMtu,
-			}}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedLedger := &mgrMocks.VlanLedgerStore{}
+			fakeUpLink := &FakeLink{netlink.LinkAttrs{Name: "enp175s0f1", Index: 20}}
 
-			// deleteUplinkVlans function
 			mocked.On("LinkByName", netconf.PFName).Return(fakeUpLink, nil)
-			// link is part of a bond but that bond is not part of the bridge!
-			mocked.On("LinkByIndex", fakeUpLink.Attrs().MasterIndex).Return(fakeBondUpLink, nil)
-			// bond has no master, GetParentBridgeForLink will fail
+			mockedLock.On("Lock").Return(nil)
+			mockedLedger.On("Load").Return(nil, errors.New("broken ledger"))
+			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mocked}
-			err := m.deleteUplinkVlans(netconf)
-			Expect(err).To(HaveOccurred())
+			m := manager{nLink: mocked, vlanUplinkLock: mockedLock, vlanLedger: mockedLedger}
+			Expect(m.deleteUplinkVlans(netconf)).To(HaveOccurred())
 			mocked.AssertExpectations(t)
+			mockedLedger.AssertExpectations(t)
 		})
 		It("Detaching dummy link from the bridge (failure)", func() {
 			mocked := &utilsMocks.Netlink{}
@@ -869,7 +1578,7 @@ var _ = Describe("Manager", func() {
 			fakeLink := &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{{ID: 3, Mac: origMac}}}}
 
 			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
-
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{}, nil)
 			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac).Return(nil)
 
 			m := manager{nLink: mocked}
@@ -879,4 +1588,647 @@ var _ = Describe("Manager", func() {
 			Expect(netconf.OrigVfState.AdminMAC).To(Equal(origMac.String()))
 		})
 	})
+	Context("Checking ApplyVFConfig and ResetVFConfig functions - MAC strategy", func() {
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+			port     *netlink.DevlinkPort
+			fnAttrs  func(mac net.HardwareAddr) netlink.DevlinkPortFnSetAttrs
+		)
+		origMac, _ := net.ParseMAC("aa:f3:8d:65:1b:d4")
+		newMac, _ := net.ParseMAC("d2:fc:22:a7:0d:e8")
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        3,
+				Representor: "pf0vf3",
+				MAC:         newMac.String(),
+			}
+			mocked = &utilsMocks.Netlink{}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{{ID: 3}}}}
+			port = &netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:af:00.1", PortIndex: 65540,
+				NetdeviceName: "pf0vf3", Fn: &netlink.DevlinkPortFn{HwAddr: origMac}}
+			fnAttrs = func(mac net.HardwareAddr) netlink.DevlinkPortFnSetAttrs {
+				return netlink.DevlinkPortFnSetAttrs{FnAttrs: netlink.DevlinkPortFn{HwAddr: mac}, HwAddrValid: true}
+			}
+			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
+		})
+		It("Sets and restores MAC through devlink port function (success)", func() {
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{
+				{NetdeviceName: "pf0vf0", Fn: &netlink.DevlinkPortFn{}}, port}, nil)
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65540), fnAttrs(newMac)).Return(nil).Once()
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.AdminMAC).To(Equal(origMac.String()))
+
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65540), fnAttrs(origMac)).Return(nil).Once()
+			Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac)
+		})
+		It("Falls back to legacy way if hw_addr is not supported in auto mode (success)", func() {
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65540), fnAttrs(newMac)).
+				Return(unix.EOPNOTSUPP)
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac).Return(nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Uses legacy way in legacy mode (success)", func() {
+			netconf.MACStrategy = "legacy"
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac).Return(nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "DevLinkGetAllPortList")
+		})
+		It("Port function is not available in devlink mode (failure)", func() {
+			netconf.MACStrategy = "devlink"
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{{NetdeviceName: "pf0vf3"}}, nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac)
+		})
+		It("Failed to set hw_addr in devlink mode (failure)", func() {
+			netconf.MACStrategy = "devlink"
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65540), fnAttrs(newMac)).
+				Return(unix.EOPNOTSUPP)
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac)
+		})
+	})
+	Context("Checking ApplyVFConfig and ResetVFConfig functions - VF administrative settings", func() {
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		BeforeEach(func() {
+			minRate := 100
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID:  "0000:af:06.0",
+					SpoofChk:  "off",
+					Trust:     "on",
+					LinkState: "enable",
+					MinTxRate: &minRate,
+				},
+				PFName: "enp175s0f1",
+				VFID:   3,
+			}
+			mocked = &utilsMocks.Netlink{}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{{
+				ID: 3, Spoofchk: true, Trust: 0, LinkState: netlink.VF_LINK_STATE_AUTO, MaxTxRate: 1000}}}}
+			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
+		})
+		It("Applies and restores VF settings (success)", func() {
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 3, false).Return(nil).Once()
+			mocked.On("LinkSetVfTrust", fakeLink, 3, true).Return(nil).Once()
+			mocked.On("LinkSetVfState", fakeLink, 3, netlink.VF_LINK_STATE_ENABLE).Return(nil).Once()
+			// max rate is not configured, original value is kept
+			mocked.On("LinkSetVfRate", fakeLink, 3, 100, 1000).Return(nil).Once()
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState).To(Equal(types.VfState{
+				AdminMAC: "", SpoofChk: true, Trust: false, LinkState: netlink.VF_LINK_STATE_AUTO, MaxTxRate: 1000}))
+			mocked.AssertExpectations(t)
+
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 3, true).Return(nil).Once()
+			mocked.On("LinkSetVfTrust", fakeLink, 3, false).Return(nil).Once()
+			mocked.On("LinkSetVfState", fakeLink, 3, netlink.VF_LINK_STATE_AUTO).Return(nil).Once()
+			mocked.On("LinkSetVfRate", fakeLink, 3, 0, 1000).Return(nil).Once()
+			Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Setting is rejected by switchdev driver (failure)", func() {
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 3, false).Return(nil)
+			mocked.On("LinkSetVfTrust", fakeLink, 3, true).Return(unix.EOPNOTSUPP)
+
+			m := manager{nLink: mocked}
+			err := m.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rejected by the driver of PF enp175s0f1"))
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking CheckRepresentor function", func() {
+		var (
+			netconf    *types.PluginConf
+			mockedNl   *utilsMocks.Netlink
+			fakeBridge *netlink.Bridge
+			fakeLink   *FakeLink
+			vlanInfo   map[int32][]*nl.BridgeVlanInfo
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					Vlan: 100,
+				},
+				Representor:  "dummylink",
+				ActualBridge: "bridge1",
+				MTU:          2000,
+				Trunk:        []int{4, 6},
+			}
+			mockedNl = &utilsMocks.Netlink{}
+			fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{
+				Name:        netconf.Representor,
+				Index:       10,
+				MasterIndex: 1000,
+				MTU:         2000,
+				Flags:       net.FlagUp,
+			}}
+			vlanInfo = map[int32][]*nl.BridgeVlanInfo{
+				10: {{Flags: nl.BRIDGE_VLAN_INFO_PVID | nl.BRIDGE_VLAN_INFO_UNTAGGED, Vid: 100},
+					{Flags: 0, Vid: 4},
+					{Flags: 0, Vid: 6}},
+			}
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		It("Representor matches config (success)", func() {
+			mockedNl.On("BridgeVlanList").Return(vlanInfo, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Representor is not attached to the bridge (failure)", func() {
+			fakeLink.MasterIndex = 0
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("not attached")))
+		})
+		It("Representor is down (failure)", func() {
+			fakeLink.Flags = 0
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("is down")))
+		})
+		It("Representor PVID mismatch (failure)", func() {
+			vlanInfo[10][0].Vid = 200
+			mockedNl.On("BridgeVlanList").Return(vlanInfo, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("PVID VLAN 200, expected 100")))
+		})
+		It("Representor trunk VLAN missing (failure)", func() {
+			vlanInfo[10] = vlanInfo[10][:2]
+			mockedNl.On("BridgeVlanList").Return(vlanInfo, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("missing trunk VLANs [6]")))
+		})
+		It("Representor has unexpected trunk VLAN (failure)", func() {
+			vlanInfo[10] = append(vlanInfo[10], &nl.BridgeVlanInfo{Vid: 8})
+			mockedNl.On("BridgeVlanList").Return(vlanInfo, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("unexpected trunk VLANs [8]")))
+		})
+	})
+	Context("Checking CheckVF function", func() {
+		var (
+			netconf   *types.PluginConf
+			mockedNl  *utilsMocks.Netlink
+			fakeLink  *FakeLink
+			contIface *current.Interface
+			ips       []*current.IPConfig
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				MTU:         2000,
+				ContIFNames: "net1",
+			}
+			mockedNl = &utilsMocks.Netlink{}
+			fakeMac, _ := net.ParseMAC("6e:16:06:0e:b7:e9")
+			fakeLink = &FakeLink{netlink.LinkAttrs{
+				Name:         "net1",
+				HardwareAddr: fakeMac,
+				MTU:          2000,
+			}}
+			contIface = &current.Interface{Name: "net1", Mac: "6e:16:06:0e:b7:e9", Sandbox: "/proc/4123/ns/net"}
+			_, ipNet, _ := net.ParseCIDR("192.168.100.101/24")
+			ipNet.IP = net.ParseIP("192.168.100.101").To4()
+			ips = []*current.IPConfig{{Address: *ipNet}}
+			mockedNl.On("LinkByName", "net1").Return(fakeLink, nil)
+		})
+		It("VF matches config (success)", func() {
+			addr, _ := netlink.ParseAddr("192.168.100.101/24")
+			mockedNl.On("AddrList", fakeLink, netlink.FAMILY_ALL).Return([]netlink.Addr{*addr}, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckVF(netconf, contIface, ips, newFakeNs())).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("VF MAC mismatch (failure)", func() {
+			netconf.MAC = "e4:11:22:33:44:55"
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckVF(netconf, contIface, ips, newFakeNs())).To(MatchError(ContainSubstring("MAC address")))
+		})
+		It("VF MTU mismatch (failure)", func() {
+			fakeLink.MTU = 1500
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckVF(netconf, contIface, ips, newFakeNs())).To(MatchError(ContainSubstring("MTU 1500")))
+		})
+		It("VF IP is missing (failure)", func() {
+			mockedNl.On("AddrList", fakeLink, netlink.FAMILY_ALL).Return([]netlink.Addr{}, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckVF(netconf, contIface, ips, newFakeNs())).To(
+				MatchError(ContainSubstring("no IP address 192.168.100.101/24")))
+		})
+	})
+	Context("Checking CheckStatus function", func() {
+		It("Bridges exist and lock is available (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedNl.On("LinkByName", "br1").Return(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br1"}}, nil)
+			mockedLock.On("Lock").Return(nil)
+			mockedLock.On("Unlock").Return(nil)
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock}
+			Expect(m.CheckStatus([]string{"br1"})).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedLock.AssertExpectations(t)
+		})
+		It("Bridge doesn't exist (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "br1").Return(nil, errors.New("not found"))
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckStatus([]string{"br1"})).To(HaveOccurred())
+		})
+		It("Link is not a bridge (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "br1").Return(&FakeLink{netlink.LinkAttrs{Name: "br1"}}, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.CheckStatus([]string{"br1"})).To(HaveOccurred())
+		})
+		It("Lock directory is not writable (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedLock := &mgrMocks.IPCLock{}
+			mockedNl.On("LinkByName", "br1").Return(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br1"}}, nil)
+			mockedLock.On("Lock").Return(errors.New("permission denied"))
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock}
+			Expect(m.CheckStatus([]string{"br1"})).To(HaveOccurred())
+		})
+	})
+	Context("Checking AddStaticFdb function", func() {
+		var (
+			netconf  *types.PluginConf
+			fakeLink *FakeLink
+			hwaddr   net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					Vlan:      100,
+					StaticFdb: true,
+				},
+				Representor: "pf0vf0",
+				Trunk:       []int{4, 100},
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: "pf0vf0", Index: 10}}
+			hwaddr, _ = net.ParseMAC("aa:f3:8d:65:1b:d4")
+		})
+		isFdbEntry := func(vlan int) interface{} {
+			return mock.MatchedBy(func(n *netlink.Neigh) bool {
+				return n.LinkIndex == 10 && n.Vlan == vlan && n.Flags == netlink.NTF_MASTER &&
+					n.State == netlink.NUD_NOARP && n.HardwareAddr.String() == hwaddr.String()
+			})
+		}
+		It("Adds entries for PVID and trunk VLANs and disables learning (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "pf0vf0").Return(fakeLink, nil)
+			mockedNl.On("NeighAdd", isFdbEntry(100)).Return(nil).Once()
+			mockedNl.On("NeighAdd", isFdbEntry(4)).Return(nil).Once()
+
+			m := manager{nLink: mockedNl}
+			Expect(m.AddStaticFdb(netconf, hwaddr.String())).NotTo(HaveOccurred())
+			Expect(netconf.FdbMAC).To(Equal(hwaddr.String()))
+			Expect(netconf.OrigRepState.PortFlags).To(HaveKeyWithValue("learning", true))
+			Expect(utils.GetBridgePortFlag("pf0vf0", "learning")).To(BeFalse())
+			mockedNl.AssertExpectations(t)
+
+			// entries are removed and learning is restored on detach
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("NeighDel", isFdbEntry(100)).Return(nil).Once()
+			mockedNl.On("NeighDel", isFdbEntry(4)).Return(nil).Once()
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(utils.GetBridgePortFlag("pf0vf0", "learning")).To(BeTrue())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Adds entry without VLAN if VLANs are not configured (success)", func() {
+			netconf.Vlan = 0
+			netconf.Trunk = nil
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "pf0vf0").Return(fakeLink, nil)
+			mockedNl.On("NeighAdd", isFdbEntry(0)).Return(nil).Once()
+
+			m := manager{nLink: mockedNl}
+			Expect(m.AddStaticFdb(netconf, hwaddr.String())).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			Expect(utils.SetBridgePortFlag("pf0vf0", "learning", true)).NotTo(HaveOccurred())
+		})
+		It("MAC is not set (failure)", func() {
+			m := manager{nLink: &utilsMocks.Netlink{}}
+			Expect(m.AddStaticFdb(netconf, "00:00:00:00:00:00")).To(HaveOccurred())
+		})
+		It("Failed to add FDB entry (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "pf0vf0").Return(fakeLink, nil)
+			mockedNl.On("NeighAdd", mock.Anything).Return(errors.New("some error"))
+
+			m := manager{nLink: mockedNl}
+			Expect(m.AddStaticFdb(netconf, hwaddr.String())).To(HaveOccurred())
+			Expect(utils.SetBridgePortFlag("pf0vf0", "learning", true)).NotTo(HaveOccurred())
+		})
+	})
+	Context("Checking FindFdbMACConflicts function", func() {
+		var (
+			netconf  *types.PluginConf
+			mockedNl *utilsMocks.Netlink
+			m        manager
+			hwaddr   net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					Vlan: 100,
+				},
+				MAC:          "b2:ec:90:4c:5b:11",
+				Representor:  "pf0vf0",
+				ActualBridge: "br1",
+			}
+			hwaddr, _ = net.ParseMAC("b2:ec:90:4c:5b:11")
+			otherMac, _ := net.ParseMAC("b2:ec:90:4c:5b:12")
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "br1").Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br1", Index: 2}}, nil)
+			mockedNl.On("LinkByName", "pf0vf0").Return(&FakeLink{netlink.LinkAttrs{Name: "pf0vf0", Index: 10}}, nil)
+			mockedNl.On("NeighList", 0, unix.AF_BRIDGE).Return([]netlink.Neigh{
+				// own entry
+				{LinkIndex: 10, MasterIndex: 2, HardwareAddr: hwaddr, Vlan: 100},
+				// entry on other bridge
+				{LinkIndex: 30, MasterIndex: 3, HardwareAddr: hwaddr, Vlan: 100},
+				// other MAC
+				{LinkIndex: 11, MasterIndex: 2, HardwareAddr: otherMac, Vlan: 100},
+				// other VLAN
+				{LinkIndex: 11, MasterIndex: 2, HardwareAddr: hwaddr, Vlan: 200},
+				// conflict
+				{LinkIndex: 12, MasterIndex: 2, HardwareAddr: hwaddr, Vlan: 100},
+			}, nil)
+			mockedNl.On("LinkByIndex", 12).Return(&FakeLink{netlink.LinkAttrs{Name: "pf0vf2", Index: 12}}, nil)
+			m = manager{nLink: mockedNl}
+		})
+		It("Finds conflict on the same VLAN (success)", func() {
+			conflicts, err := m.FindFdbMACConflicts(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(Equal([]string{"FDB entry on port pf0vf2 vlan 100"}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Finds conflicts on all VLANs if VLANs are not configured (success)", func() {
+			netconf.Vlan = 0
+			mockedNl.On("LinkByIndex", 11).Return(&FakeLink{netlink.LinkAttrs{Name: "pf0vf1", Index: 11}}, nil)
+			conflicts, err := m.FindFdbMACConflicts(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(Equal([]string{
+				"FDB entry on port pf0vf1 vlan 200",
+				"FDB entry on port pf0vf2 vlan 100",
+			}))
+		})
+		It("Failed to list FDB entries (failure)", func() {
+			mockedNl.ExpectedCalls = nil
+			mockedNl.On("LinkByName", "br1").Return(&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 2}}, nil)
+			mockedNl.On("LinkByName", "pf0vf0").Return(&FakeLink{netlink.LinkAttrs{Index: 10}}, nil)
+			mockedNl.On("NeighList", 0, unix.AF_BRIDGE).Return(nil, errors.New("some error"))
+			_, err := m.FindFdbMACConflicts(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetHostInterfaces function", func() {
+		var (
+			netconf *types.PluginConf
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				Representor:  "eth5",
+				ActualBridge: "br1",
+			}
+		})
+		It("Returns representor and bridge (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			repMac, _ := net.ParseMAC("c6:c8:7f:1f:21:90")
+			brMac, _ := net.ParseMAC("aa:f3:8d:65:1b:d4")
+			mockedNl.On("LinkByName", "eth5").Return(
+				&FakeLink{netlink.LinkAttrs{Name: "eth5", HardwareAddr: repMac}}, nil)
+			mockedNl.On("LinkByName", "br1").Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br1", HardwareAddr: brMac}}, nil)
+			m := manager{nLink: mockedNl}
+			ifaces, err := m.GetHostInterfaces(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ifaces).To(Equal([]*current.Interface{
+				{Name: "eth5", Mac: "c6:c8:7f:1f:21:90"},
+				{Name: "br1", Mac: "aa:f3:8d:65:1b:d4"},
+			}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Representor doesn't exist (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "eth5").Return(nil, errors.New("not found"))
+			m := manager{nLink: mockedNl}
+			_, err := m.GetHostInterfaces(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Bridge doesn't exist (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", "eth5").Return(&FakeLink{netlink.LinkAttrs{Name: "eth5"}}, nil)
+			mockedNl.On("LinkByName", "br1").Return(nil, errors.New("not found"))
+			m := manager{nLink: mockedNl}
+			_, err := m.GetHostInterfaces(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SF support", func() {
+		var (
+			netconf *types.PluginConf
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "mlx5_core.sf.2",
+				},
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				IsSF:         true,
+				SFNum:        88,
+				MAC:          "d2:fc:22:a7:0d:e8",
+				OrigVfState: types.VfState{
+					HostIFName:   "enp175s0f1s2",
+					EffectiveMAC: "c6:c8:7f:1f:21:90",
+				},
+			}
+		})
+		It("Attaches SF representor to the bridge (success)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: "en3f0pf0sf88"}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedSr.On("GetSfRepresentor", netconf.PFName, netconf.SFNum).Return(fakeLink.Name, nil)
+			mockedNl.On("LinkByName", fakeLink.Name).Return(fakeLink, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.Representor).To(Equal(fakeLink.Name))
+			mockedNl.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+			mockedSr.AssertNotCalled(t, "GetVfRepresentor", netconf.PFName, netconf.VFID)
+		})
+		It("Failed to get SF representor (failure)", func() {
+			mockedNl := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedSr.On("GetSfRepresentor", netconf.PFName, netconf.SFNum).Return("", errors.New("not found"))
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			err := m.AttachRepresentor(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("SF's 88 representor"))
+		})
+		It("Sets and restores SF MAC through devlink port function (success)", func() {
+			mocked := &utilsMocks.Netlink{}
+			netconf.Representor = "en3f0pf0sf88"
+			origMac, _ := net.ParseMAC("aa:f3:8d:65:1b:d4")
+			newMac, _ := net.ParseMAC(netconf.MAC)
+			port := &netlink.DevlinkPort{BusName: "pci", DeviceName: "0000:af:00.1", PortIndex: 98304,
+				NetdeviceName: netconf.Representor, Fn: &netlink.DevlinkPortFn{HwAddr: origMac}}
+
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(98304), netlink.DevlinkPortFnSetAttrs{
+				FnAttrs: netlink.DevlinkPortFn{HwAddr: newMac}, HwAddrValid: true}).Return(nil).Once()
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.AdminMAC).To(Equal(origMac.String()))
+
+			mocked.On("LinkByName", netconf.PFName).Return(&FakeLink{netlink.LinkAttrs{Name: netconf.PFName}}, nil)
+			mocked.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(98304), netlink.DevlinkPortFnSetAttrs{
+				FnAttrs: netlink.DevlinkPortFn{HwAddr: origMac}, HwAddrValid: true}).Return(nil).Once()
+			Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("SF MAC can't be set without devlink port function (failure)", func() {
+			mocked := &utilsMocks.Netlink{}
+			netconf.Representor = "en3f0pf0sf88"
+			mocked.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{}, nil)
+
+			m := manager{nLink: mocked}
+			Expect(m.ApplyVFConfig(netconf)).To(HaveOccurred())
+		})
+		It("Restores SF name and MAC (success)", func() {
+			netconf.OrigVfState.HostIFName = "eth10"
+			mocked := &utilsMocks.Netlink{}
+			mockedSr := &utilsMocks.Sriovnet{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1s2"}}
+			origEffMac, _ := net.ParseMAC(netconf.OrigVfState.EffectiveMAC)
+
+			mockedSr.On("GetNetDevicesFromAux", netconf.DeviceID).Return([]string{"enp175s0f1s2"}, nil)
+			mocked.On("LinkByName", "enp175s0f1s2").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "eth10").Return(nil)
+			mocked.On("LinkSetHardwareAddr", fakeLink, origEffMac).Return(nil)
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RestoreVF(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+	})
+	Context("Checking RestoreVF function", func() {
+		var (
+			netconf *types.PluginConf
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				MAC:         "aa:f3:8d:65:1b:d4",
+				MTU:         1600,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName:   "enp175s6",
+					EffectiveMAC: "c6:c8:7f:1f:21:90",
+					MTU:          1500,
+				},
+			}
+		})
+		It("VF has original name, restores MAC and MTU (success)", func() {
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			origEffMac, _ := net.ParseMAC(netconf.OrigVfState.EffectiveMAC)
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetHardwareAddr", fakeLink, origEffMac).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, 1500).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.RestoreVF(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("VF has container name, renames it back (success)", func() {
+			netconf.OrigVfState.HostIFName = "eth10"
+			netconf.MAC = ""
+			netconf.MTU = 0
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "eth10").Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.RestoreVF(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("VF is not in init netns (failure)", func() {
+			netconf.DeviceID = "0000:af:07.0"
+			mocked := &utilsMocks.Netlink{}
+			m := manager{nLink: mocked}
+			Expect(m.RestoreVF(netconf)).To(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking IsVFInInitNetns function", func() {
+		It("VF netdevice is in init netns", func() {
+			m := manager{}
+			Expect(m.IsVFInInitNetns(&types.PluginConf{NetConf: types.NetConf{DeviceID: "0000:af:06.0"}})).To(BeTrue())
+		})
+		It("VF netdevice is not in init netns", func() {
+			m := manager{}
+			Expect(m.IsVFInInitNetns(&types.PluginConf{NetConf: types.NetConf{DeviceID: "0000:af:07.0"}})).To(BeFalse())
+		})
+	})
 })
@@ -23,6 +23,27 @@ func (_m *IPCLock) Lock() error {
 	return r0
 }
 
+// TryLock provides a mock function with given fields:
+func (_m *IPCLock) TryLock() (bool, error) {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Unlock provides a mock function with given fields:
 func (_m *IPCLock) Unlock() error {
 	ret := _m.Called()
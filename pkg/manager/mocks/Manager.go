@@ -6,6 +6,8 @@ import (
 	ns "github.com/containernetworking/plugins/pkg/ns"
 	types "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	mock "github.com/stretchr/testify/mock"
+
+	types100 "github.com/containernetworking/cni/pkg/types/100"
 )
 
 // Manager is an autogenerated mock type for the Manager type
@@ -13,6 +15,34 @@ type Manager struct {
 	mock.Mock
 }
 
+// AddStaticFdb provides a mock function with given fields: conf, mac
+func (_m *Manager) AddStaticFdb(conf *types.PluginConf, mac string) error {
+	ret := _m.Called(conf, mac)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string) error); ok {
+		r0 = rf(conf, mac)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AnnounceAddresses provides a mock function with given fields: conf, ifName, ips
+func (_m *Manager) AnnounceAddresses(conf *types.PluginConf, ifName string, ips []*types100.IPConfig) error {
+	ret := _m.Called(conf, ifName, ips)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string, []*types100.IPConfig) error); ok {
+		r0 = rf(conf, ifName, ips)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ApplyVFConfig provides a mock function with given fields: conf
 func (_m *Manager) ApplyVFConfig(conf *types.PluginConf) error {
 	ret := _m.Called(conf)
@@ -41,6 +71,62 @@ func (_m *Manager) AttachRepresentor(conf *types.PluginConf) error {
 	return r0
 }
 
+// BindVFDriver provides a mock function with given fields: conf
+func (_m *Manager) BindVFDriver(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckRepresentor provides a mock function with given fields: conf
+func (_m *Manager) CheckRepresentor(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckStatus provides a mock function with given fields: bridges
+func (_m *Manager) CheckStatus(bridges []string) error {
+	ret := _m.Called(bridges)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(bridges)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckVF provides a mock function with given fields: conf, contIface, ips, netns
+func (_m *Manager) CheckVF(conf *types.PluginConf, contIface *types100.Interface, ips []*types100.IPConfig, netns ns.NetNS) error {
+	ret := _m.Called(conf, contIface, ips, netns)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, *types100.Interface, []*types100.IPConfig, ns.NetNS) error); ok {
+		r0 = rf(conf, contIface, ips, netns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DetachRepresentor provides a mock function with given fields: conf
 func (_m *Manager) DetachRepresentor(conf *types.PluginConf) error {
 	ret := _m.Called(conf)
@@ -55,6 +141,103 @@ func (_m *Manager) DetachRepresentor(conf *types.PluginConf) error {
 	return r0
 }
 
+// FindFdbMACConflicts provides a mock function with given fields: conf
+func (_m *Manager) FindFdbMACConflicts(conf *types.PluginConf) ([]string, error) {
+	ret := _m.Called(conf)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) []string); ok {
+		r0 = rf(conf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.PluginConf) error); ok {
+		r1 = rf(conf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHostInterfaces provides a mock function with given fields: conf
+func (_m *Manager) GetHostInterfaces(conf *types.PluginConf) ([]*types100.Interface, error) {
+	ret := _m.Called(conf)
+
+	var r0 []*types100.Interface
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) []*types100.Interface); ok {
+		r0 = rf(conf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*types100.Interface)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.PluginConf) error); ok {
+		r1 = rf(conf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUplinkVlanLedger provides a mock function with given fields:
+func (_m *Manager) GetUplinkVlanLedger() (types.UplinkVlanLedger, error) {
+	ret := _m.Called()
+
+	var r0 types.UplinkVlanLedger
+	if rf, ok := ret.Get(0).(func() types.UplinkVlanLedger); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.UplinkVlanLedger)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsVFInInitNetns provides a mock function with given fields: conf
+func (_m *Manager) IsVFInInitNetns(conf *types.PluginConf) bool {
+	ret := _m.Called(conf)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) bool); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Preflight provides a mock function with given fields: conf
+func (_m *Manager) Preflight(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReleaseVF provides a mock function with given fields: conf, podifName, cid, netns
 func (_m *Manager) ReleaseVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) error {
 	ret := _m.Called(conf, podifName, cid, netns)
@@ -69,6 +252,20 @@ func (_m *Manager) ReleaseVF(conf *types.PluginConf, podifName string, cid strin
 	return r0
 }
 
+// RepairUplinkVlanLedger provides a mock function with given fields: isAttachment
+func (_m *Manager) RepairUplinkVlanLedger(isAttachment func(string) (bool, error)) error {
+	ret := _m.Called(isAttachment)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(string) (bool, error)) error); ok {
+		r0 = rf(isAttachment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ResetVFConfig provides a mock function with given fields: conf
 func (_m *Manager) ResetVFConfig(conf *types.PluginConf) error {
 	ret := _m.Called(conf)
@@ -83,6 +280,55 @@ func (_m *Manager) ResetVFConfig(conf *types.PluginConf) error {
 	return r0
 }
 
+// RestoreVF provides a mock function with given fields: conf
+func (_m *Manager) RestoreVF(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreVFDriver provides a mock function with given fields: conf
+func (_m *Manager) RestoreVFDriver(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetupUserspaceVF provides a mock function with given fields: conf
+func (_m *Manager) SetupUserspaceVF(conf *types.PluginConf) (string, error) {
+	ret := _m.Called(conf)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) string); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.PluginConf) error); ok {
+		r1 = rf(conf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetupVF provides a mock function with given fields: conf, podifName, cid, netns
 func (_m *Manager) SetupVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) (string, error) {
 	ret := _m.Called(conf, podifName, cid, netns)
@@ -103,3 +349,17 @@ func (_m *Manager) SetupVF(conf *types.PluginConf, podifName string, cid string,
 
 	return r0, r1
 }
+
+// WaitForCarrier provides a mock function with given fields: conf, podifName, netns
+func (_m *Manager) WaitForCarrier(conf *types.PluginConf, podifName string, netns ns.NetNS) error {
+	ret := _m.Called(conf, podifName, netns)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string, ns.NetNS) error); ok {
+		r0 = rf(conf, podifName, netns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+const (
+	// eswitchModeSwitchdev is a devlink eswitch mode which is required for VF representors
+	eswitchModeSwitchdev = "switchdev"
+	// devlinkBusPci is a devlink bus name of PCI devices
+	devlinkBusPci = "pci"
+)
+
+// Preflight validates that the VF can be attached to the bridge before anything is changed on the host:
+// eswitch of the PF must be in switchdev mode, the VF representor must belong to the eswitch of the uplink
+// and the representor must not be attached to another bridge
+func (m *manager) Preflight(conf *types.PluginConf) error {
+	if err := m.checkEswitchMode(conf); err != nil {
+		return err
+	}
+
+	rep, err := m.getRepresentor(conf)
+	if err != nil {
+		return err
+	}
+
+	if err = checkSwitchID(conf.PFName, rep); err != nil {
+		return err
+	}
+
+	return m.checkRepresentorMaster(conf, rep)
+}
+
+// checkEswitchMode checks devlink eswitch mode of the PF
+func (m *manager) checkEswitchMode(conf *types.PluginConf) error {
+	pfPci, err := utils.GetPfPciAddress(conf.PFName)
+	if err != nil {
+		return err
+	}
+	dev, err := m.nLink.DevLinkGetDeviceByName(devlinkBusPci, pfPci)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink device %s/%s of PF %s: %v", devlinkBusPci, pfPci, conf.PFName, err)
+	}
+	if dev.Attrs.Eswitch.Mode != eswitchModeSwitchdev {
+		return fmt.Errorf("eswitch of PF %s is in %q mode, switchdev mode is required, "+
+			"change it with \"devlink dev eswitch set %s/%s mode switchdev\"",
+			conf.PFName, dev.Attrs.Eswitch.Mode, devlinkBusPci, pfPci)
+	}
+	log.Debug().Msgf("Eswitch of PF %s is in switchdev mode", conf.PFName)
+	return nil
+}
+
+// checkSwitchID checks that the representor and the uplink are ports of the same eswitch
+func checkSwitchID(uplink, rep string) error {
+	uplinkSwitchID, err := utils.GetPhysSwitchID(uplink)
+	if err != nil {
+		return err
+	}
+	repSwitchID, err := utils.GetPhysSwitchID(rep)
+	if err != nil {
+		return err
+	}
+	if uplinkSwitchID != repSwitchID {
+		return fmt.Errorf("representor %s belongs to eswitch %s, but uplink %s belongs to eswitch %s, "+
+			"check that deviceID matches the PF", rep, repSwitchID, uplink, uplinkSwitchID)
+	}
+	return nil
+}
+
+// checkRepresentorMaster checks that the representor is not attached to another bridge or other master device
+func (m *manager) checkRepresentorMaster(conf *types.PluginConf, rep string) error {
+	repLink, err := m.nLink.LinkByName(rep)
+	if err != nil {
+		return fmt.Errorf("failed to get representor link %s: %v", rep, err)
+	}
+	if repLink.Attrs().MasterIndex == 0 {
+		return nil
+	}
+	master, err := m.nLink.LinkByIndex(repLink.Attrs().MasterIndex)
+	if err != nil {
+		return fmt.Errorf("failed to get master of representor %s: %v", rep, err)
+	}
+	if master.Attrs().Name != conf.ActualBridge {
+		return fmt.Errorf("representor %s is already attached to %s %s, the VF may be used by another attachment, "+
+			"detach it with \"ip link set %s nomaster\" if it is stale", rep, master.Type(), master.Attrs().Name, rep)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("Preflight", func() {
+	var (
+		t        GinkgoTInterface
+		netconf  *types.PluginConf
+		mockedNl *utilsMocks.Netlink
+		mockedSr *utilsMocks.Sriovnet
+	)
+
+	switchdevDevice := func(mode string) *netlink.DevlinkDevice {
+		dev := &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+		dev.Attrs.Eswitch.Mode = mode
+		return dev
+	}
+
+	BeforeEach(func() {
+		t = GinkgoT()
+		netconf = &types.PluginConf{
+			NetConf: types.NetConf{
+				DeviceID: "0000:af:06.0",
+			},
+			PFName:       "enp175s0f1",
+			VFID:         0,
+			ActualBridge: "bridge1",
+		}
+		mockedNl = &utilsMocks.Netlink{}
+		mockedSr = &utilsMocks.Sriovnet{}
+	})
+	Context("Checking Preflight function", func() {
+		It("Representor is not attached (success)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(switchdevDevice("switchdev"), nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("pf0vf0", nil)
+			mockedNl.On("LinkByName", "pf0vf0").Return(&FakeLink{netlink.LinkAttrs{Name: "pf0vf0"}}, nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.Preflight(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("Representor is attached to the configured bridge (success)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(switchdevDevice("switchdev"), nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("pf0vf0", nil)
+			mockedNl.On("LinkByName", "pf0vf0").Return(
+				&FakeLink{netlink.LinkAttrs{Name: "pf0vf0", MasterIndex: 10}}, nil)
+			mockedNl.On("LinkByIndex", 10).Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "bridge1", Index: 10}}, nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.Preflight(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Eswitch in legacy mode (failure)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(switchdevDevice("legacy"), nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			err := m.Preflight(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("devlink dev eswitch set pci/0000:af:00.1 mode switchdev"))
+			mockedSr.AssertNotCalled(t, "GetVfRepresentor", netconf.PFName, netconf.VFID)
+		})
+		It("Failed to get devlink device (failure)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(nil, errors.New("no device"))
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			Expect(m.Preflight(netconf)).To(HaveOccurred())
+		})
+		It("Representor belongs to another eswitch (failure)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(switchdevDevice("switchdev"), nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("pf1vf0", nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			err := m.Preflight(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("belongs to eswitch"))
+			mockedNl.AssertNotCalled(t, "LinkByName", "pf1vf0")
+		})
+		It("Representor is attached to another bridge (failure)", func() {
+			mockedNl.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(switchdevDevice("switchdev"), nil)
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("pf0vf0", nil)
+			mockedNl.On("LinkByName", "pf0vf0").Return(
+				&FakeLink{netlink.LinkAttrs{Name: "pf0vf0", MasterIndex: 11}}, nil)
+			mockedNl.On("LinkByIndex", 11).Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "bridge2", Index: 11}}, nil)
+
+			m := manager{nLink: mockedNl, sriov: mockedSr}
+			err := m.Preflight(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already attached to bridge bridge2"))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+// SetupUserspaceVF configures the VF with userspace driver, the VF has no netdevice so only settings
+// available through the PF and the representor are used. Returns MAC which is seen by the userspace application
+// or the guest, the MAC is generated and set as administrative MAC if the VF has no administrative MAC.
+// MTU of the VF is set by the userspace application, the representor MTU is recorded as the MTU of the VF
+func (m *manager) SetupUserspaceVF(conf *types.PluginConf) (string, error) {
+	pfLink, err := m.nLink.LinkByName(conf.PFName)
+	if err != nil {
+		return "", fmt.Errorf("failed to lookup master %q: %v", conf.PFName, err)
+	}
+
+	// traffic of the VF leaves the host through the PF
+	if conf.MTU != 0 && pfLink.Attrs().MTU < conf.MTU {
+		return "", fmt.Errorf("MTU %d is bigger than MTU %d of PF %s", conf.MTU, pfLink.Attrs().MTU, conf.PFName)
+	}
+	if err = m.setUserspaceMTU(conf); err != nil {
+		return "", err
+	}
+
+	mac := conf.MAC
+	if mac == "" {
+		vfInfo := getVfInfo(pfLink, conf.VFID)
+		if vfInfo == nil {
+			return "", fmt.Errorf("failed to find vf %d for PF %s", conf.VFID, conf.PFName)
+		}
+		if len(vfInfo.Mac) > 0 && !bytes.Equal(vfInfo.Mac, make(net.HardwareAddr, len(vfInfo.Mac))) {
+			mac = vfInfo.Mac.String()
+		} else if mac, err = m.setGeneratedUserspaceMAC(conf, pfLink); err != nil {
+			return "", err
+		}
+	}
+	conf.UserspaceMAC = mac
+	log.Debug().Msgf("VF %s with userspace driver uses MAC %q and MTU %d", conf.DeviceID, mac, conf.UserspaceMTU)
+	return mac, nil
+}
+
+// setUserspaceMTU records MTU of the representor as MTU of the VF with userspace driver,
+// configured MTU is set on the representor when it is attached to the bridge
+func (m *manager) setUserspaceMTU(conf *types.PluginConf) error {
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor %s link: %v", conf.Representor, err)
+	}
+	if conf.MTU != 0 && rep.Attrs().MTU != conf.MTU {
+		return fmt.Errorf("representor %s has MTU %d, expected %d", conf.Representor, rep.Attrs().MTU, conf.MTU)
+	}
+	conf.UserspaceMTU = rep.Attrs().MTU
+	return nil
+}
+
+// setGeneratedUserspaceMAC generates MAC for the VF without administrative MAC and sets it through the PF,
+// the MAC is recorded as configured MAC, so the original administrative MAC is restored on DEL
+func (m *manager) setGeneratedUserspaceMAC(conf *types.PluginConf, pfLink netlink.Link) (string, error) {
+	prefix := conf.MACPrefix
+	if prefix == "" {
+		prefix = utils.DefaultMACPrefix
+	}
+	mac, err := utils.GenerateMAC(prefix, conf.Name, conf.AttachmentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate MAC for VF %s: %v", conf.DeviceID, err)
+	}
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+
+	port, err := m.getVfDevlinkPort(conf)
+	if err != nil {
+		return "", err
+	}
+	if port != nil && len(port.Fn.HwAddr) > 0 {
+		conf.OrigVfState.AdminMAC = port.Fn.HwAddr.String()
+	}
+	if err = m.setVfMAC(conf, pfLink, port, hwaddr); err != nil {
+		return "", fmt.Errorf("failed to set MAC address to %s: %v", hwaddr, err)
+	}
+	log.Info().Msgf("Generated MAC %s for VF %s with userspace driver", mac, conf.DeviceID)
+	conf.MAC = mac
+	return mac, nil
+}
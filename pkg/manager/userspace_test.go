@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+	utilsMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
+)
+
+var _ = Describe("Userspace VF", func() {
+	var (
+		t        GinkgoTInterface
+		netconf  *types.PluginConf
+		mockedNl *utilsMocks.Netlink
+		m        manager
+	)
+	adminMac, _ := net.ParseMAC("aa:f3:8d:65:1b:d4")
+
+	BeforeEach(func() {
+		t = GinkgoT()
+		netconf = &types.PluginConf{
+			NetConf: types.NetConf{
+				DeviceID: "0000:af:06.0",
+			},
+			PFName:            "enp175s0f1",
+			VFID:              3,
+			Representor:       "enp175s0f1_3",
+			AttachmentID:      "mynet-cid-net1",
+			IsUserspaceDriver: true,
+		}
+		netconf.Name = "mynet"
+		mockedNl = &utilsMocks.Netlink{}
+		m = manager{nLink: mockedNl}
+	})
+	AfterEach(func() {
+		mockedNl.AssertExpectations(t)
+	})
+	Context("Checking SetupUserspaceVF function", func() {
+		It("Configured MAC is used", func() {
+			netconf.MAC = "d2:fc:22:a7:0d:e8"
+			netconf.MTU = 9000
+			mockedNl.On("LinkByName", "enp175s0f1").Return(
+				&FakeLink{netlink.LinkAttrs{MTU: 9000, Vfs: []netlink.VfInfo{{ID: 3, Mac: adminMac}}}}, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 9000}}, nil)
+			mac, err := m.SetupUserspaceVF(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac).To(Equal("d2:fc:22:a7:0d:e8"))
+			Expect(netconf.UserspaceMAC).To(Equal("d2:fc:22:a7:0d:e8"))
+			Expect(netconf.UserspaceMTU).To(Equal(9000))
+		})
+		It("Administrative MAC is used", func() {
+			mockedNl.On("LinkByName", "enp175s0f1").Return(
+				&FakeLink{netlink.LinkAttrs{MTU: 1500, Vfs: []netlink.VfInfo{{ID: 3, Mac: adminMac}}}}, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			mac, err := m.SetupUserspaceVF(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac).To(Equal(adminMac.String()))
+			Expect(netconf.MAC).To(BeEmpty())
+		})
+		It("Administrative MAC is not set, MAC is generated and set through the PF", func() {
+			netconf.MACStrategy = utils.MACStrategyLegacy
+			expected, err := utils.GenerateMAC(utils.DefaultMACPrefix, "mynet", "mynet-cid-net1")
+			Expect(err).NotTo(HaveOccurred())
+			hwaddr, err := net.ParseMAC(expected)
+			Expect(err).NotTo(HaveOccurred())
+			pfLink := &FakeLink{netlink.LinkAttrs{MTU: 1500,
+				Vfs: []netlink.VfInfo{{ID: 3, Mac: net.HardwareAddr{0, 0, 0, 0, 0, 0}}}}}
+			mockedNl.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			mockedNl.On("LinkSetVfHardwareAddr", pfLink, 3, hwaddr).Return(nil)
+			mac, err := m.SetupUserspaceVF(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac).To(Equal(expected))
+			Expect(netconf.UserspaceMAC).To(Equal(expected))
+			// administrative MAC is restored on DEL
+			Expect(netconf.MAC).To(Equal(expected))
+		})
+		It("Setting generated MAC failed (failure)", func() {
+			netconf.MACStrategy = utils.MACStrategyLegacy
+			pfLink := &FakeLink{netlink.LinkAttrs{MTU: 1500,
+				Vfs: []netlink.VfInfo{{ID: 3, Mac: net.HardwareAddr{0, 0, 0, 0, 0, 0}}}}}
+			mockedNl.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			mockedNl.On("LinkSetVfHardwareAddr", pfLink, 3, mock.Anything).Return(errors.New("some error"))
+			_, err := m.SetupUserspaceVF(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(netconf.MAC).To(BeEmpty())
+		})
+		It("MTU is not configured, representor MTU is reported", func() {
+			mockedNl.On("LinkByName", "enp175s0f1").Return(
+				&FakeLink{netlink.LinkAttrs{MTU: 9000, Vfs: []netlink.VfInfo{{ID: 3, Mac: adminMac}}}}, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			_, err := m.SetupUserspaceVF(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.UserspaceMTU).To(Equal(1500))
+		})
+		It("MTU is not applied to the representor (failure)", func() {
+			netconf.MTU = 9000
+			mockedNl.On("LinkByName", "enp175s0f1").Return(
+				&FakeLink{netlink.LinkAttrs{MTU: 9000, Vfs: []netlink.VfInfo{{ID: 3, Mac: adminMac}}}}, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			_, err := m.SetupUserspaceVF(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+		It("MTU is bigger than PF MTU (failure)", func() {
+			netconf.MTU = 9000
+			mockedNl.On("LinkByName", "enp175s0f1").Return(
+				&FakeLink{netlink.LinkAttrs{MTU: 1500, Vfs: []netlink.VfInfo{{ID: 3, Mac: adminMac}}}}, nil)
+			_, err := m.SetupUserspaceVF(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+		It("VF is not found (failure)", func() {
+			mockedNl.On("LinkByName", "enp175s0f1").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			mockedNl.On("LinkByName", "enp175s0f1_3").Return(&FakeLink{netlink.LinkAttrs{MTU: 1500}}, nil)
+			_, err := m.SetupUserspaceVF(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
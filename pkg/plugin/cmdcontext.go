@@ -15,8 +15,12 @@ type cmdContext struct {
 
 	netNS  ns.NetNS
 	result *current.Result
+	// index of the container interface in result.Interfaces
+	contIfIndex int
 
 	errorHandlers []func()
+	// releases the attachment lock, called after error handlers
+	unlock func()
 }
 
 // add register cleanup function which should be called if cmd completed with error
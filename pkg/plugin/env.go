@@ -5,6 +5,9 @@ import "github.com/containernetworking/cni/pkg/types"
 type envArgs struct {
 	types.CommonArgs
 	MAC types.UnmarshallableString `json:"mac,omitempty"`
+	// pod namespace and name provided by kubernetes runtimes
+	K8S_POD_NAMESPACE types.UnmarshallableString //nolint:revive,stylecheck
+	K8S_POD_NAME      types.UnmarshallableString //nolint:revive,stylecheck
 }
 
 func getEnvArgs(envArgsString string) (*envArgs, error) {
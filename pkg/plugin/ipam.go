@@ -1,6 +1,9 @@
 package plugin
 
 import (
+	"context"
+
+	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ipam"
@@ -10,6 +13,9 @@ import (
 type IPAM interface {
 	ExecAdd(plugin string, netconf []byte) (types.Result, error)
 	ExecDel(plugin string, netconf []byte) error
+	ExecCheck(plugin string, netconf []byte) error
+	ExecGC(plugin string, netconf []byte) error
+	ExecStatus(plugin string, netconf []byte) error
 	ConfigureIface(ifName string, res *current.Result) error
 }
 
@@ -26,6 +32,21 @@ func (i *ipamWrapper) ExecDel(plugin string, netconf []byte) error {
 	return ipam.ExecDel(plugin, netconf)
 }
 
+// ExecCheck is a wrapper for ipam.ExecCheck
+func (i *ipamWrapper) ExecCheck(plugin string, netconf []byte) error {
+	return ipam.ExecCheck(plugin, netconf)
+}
+
+// ExecGC is a wrapper for invoke.DelegateGC
+func (i *ipamWrapper) ExecGC(plugin string, netconf []byte) error {
+	return invoke.DelegateGC(context.TODO(), plugin, netconf, nil)
+}
+
+// ExecStatus is a wrapper for invoke.DelegateStatus
+func (i *ipamWrapper) ExecStatus(plugin string, netconf []byte) error {
+	return invoke.DelegateStatus(context.TODO(), plugin, netconf, nil)
+}
+
 // ConfigureIface is a wrapper for ipam.ConfigureIface
 func (i *ipamWrapper) ConfigureIface(ifName string, res *current.Result) error {
 	return ipam.ConfigureIface(ifName, res)
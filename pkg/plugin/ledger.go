@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
+)
+
+// DumpVlanLedger writes the uplink VLAN ledger in JSON format
+func (p *Plugin) DumpVlanLedger(w io.Writer) error {
+	ledger, err := p.manager.GetUplinkVlanLedger()
+	if err != nil {
+		return fmt.Errorf("failed to load uplink VLAN ledger: %v", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ledger)
+}
+
+// RepairVlanLedger removes owners of uplink VLANs which are not attachments from the ledger,
+// VLANs which have no owners left are removed from the uplinks
+func (p *Plugin) RepairVlanLedger() error {
+	return p.manager.RepairUplinkVlanLedger(p.isAttachment)
+}
+
+// isAttachment returns true if the attachment is cached or its lock is held by another instance,
+// ADD records owners of uplink VLANs before the attachment is cached
+func (p *Plugin) isAttachment(owner string) (bool, error) {
+	ref := cache.StateRef(owner)
+	lock := p.attachmentLock(ref)
+	locked, err := lock.TryLock()
+	if err != nil {
+		return false, fmt.Errorf("failed to lock attachment %s: %v", ref, err)
+	}
+	if !locked {
+		return true, nil
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	refs, err := p.cache.List("")
+	if err != nil {
+		return false, fmt.Errorf("failed to list cached attachments: %v", err)
+	}
+	for _, r := range refs {
+		if r == ref {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,30 @@
+package plugin
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager"
+)
+
+// attachmentLockDir is a subdirectory of the cache directory with attachment lock files,
+// lock files are not removed since another process may wait on the lock
+const attachmentLockDir = "locks"
+
+// newAttachmentLock returns file lock of the attachment which serializes ADD, DEL, GC and reconcile
+// of the same attachment
+func newAttachmentLock(ref cache.StateRef) manager.IPCLock {
+	return manager.NewIPCLock(filepath.Join(cache.CacheDir, attachmentLockDir, string(ref)+".lock"))
+}
+
+// lockAttachment takes the attachment lock, returns function which releases the lock
+func (p *Plugin) lockAttachment(ref cache.StateRef) (func(), error) {
+	lock := p.attachmentLock(ref)
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock attachment %s: %v", ref, err)
+	}
+	return func() {
+		_ = lock.Unlock()
+	}, nil
+}
@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+// checkMACConflicts looks for the VF MAC in the bridge FDB and in cached attachments on the same bridge and VLANs,
+// a conflict fails ADD or is only logged depending on macConflictPolicy
+func (p *Plugin) checkMACConflicts(pluginConf *localtypes.PluginConf) error {
+	if pluginConf.MAC == "" || pluginConf.MACConflictPolicy == utils.MACConflictPolicyIgnore {
+		return nil
+	}
+	failOnConflict := pluginConf.MACConflictPolicy == utils.MACConflictPolicyFail
+
+	conflicts, err := p.manager.FindFdbMACConflicts(pluginConf)
+	if err != nil {
+		err = fmt.Errorf("failed to check MAC %s in FDB of bridge %s: %v", pluginConf.MAC, pluginConf.ActualBridge, err)
+		if failOnConflict {
+			return err
+		}
+		log.Warn().Msg(err.Error())
+	}
+	conflicts = append(conflicts, p.findCachedMACConflicts(pluginConf)...)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("MAC %s is already used on bridge %s: %s",
+		pluginConf.MAC, pluginConf.ActualBridge, strings.Join(conflicts, ", "))
+	if failOnConflict {
+		return errors.New(msg)
+	}
+	log.Warn().Msg(msg)
+	return nil
+}
+
+// findCachedMACConflicts returns cached attachments which use the same MAC on the same bridge and VLANs
+func (p *Plugin) findCachedMACConflicts(pluginConf *localtypes.PluginConf) []string {
+	refs, err := p.cache.List("")
+	if err != nil {
+		log.Warn().Msgf("failed to list cached attachments: %v", err)
+		return nil
+	}
+	mac, _ := net.ParseMAC(pluginConf.MAC)
+
+	var conflicts []string
+	for _, ref := range refs {
+		cached := &localtypes.PluginConf{}
+		if loadErr := p.loadAttachment(ref, cached); loadErr != nil {
+			log.Warn().Msgf("failed to load cached attachment %s, skip it: %v", ref, loadErr)
+			continue
+		}
+		if cached.AttachmentID == pluginConf.AttachmentID || cached.ActualBridge != pluginConf.ActualBridge {
+			continue
+		}
+		cachedMAC, parseErr := net.ParseMAC(cached.MAC)
+		if parseErr != nil || cachedMAC.String() != mac.String() || !vlansOverlap(cached, pluginConf) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("attachment %s", ref))
+	}
+	return conflicts
+}
+
+// vlansOverlap returns true if the attachments share a VLAN, attachment without VLANs overlaps with any attachment
+func vlansOverlap(a, b *localtypes.PluginConf) bool {
+	vlansA, vlansB := attachmentVlans(a), attachmentVlans(b)
+	if len(vlansA) == 0 || len(vlansB) == 0 {
+		return true
+	}
+	for vlan := range vlansA {
+		if vlansB[vlan] {
+			return true
+		}
+	}
+	return false
+}
+
+func attachmentVlans(conf *localtypes.PluginConf) map[int]bool {
+	vlans := make(map[int]bool)
+	if conf.Vlan > 0 {
+		vlans[conf.Vlan] = true
+	}
+	for _, vlan := range conf.Trunk {
+		vlans[vlan] = true
+	}
+	return vlans
+}
@@ -51,6 +51,20 @@ func (_m *IPAM) ExecAdd(_a0 string, netconf []byte) (types.Result, error) {
 	return r0, r1
 }
 
+// ExecCheck provides a mock function with given fields: _a0, netconf
+func (_m *IPAM) ExecCheck(_a0 string, netconf []byte) error {
+	ret := _m.Called(_a0, netconf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []byte) error); ok {
+		r0 = rf(_a0, netconf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ExecDel provides a mock function with given fields: _a0, netconf
 func (_m *IPAM) ExecDel(_a0 string, netconf []byte) error {
 	ret := _m.Called(_a0, netconf)
@@ -64,3 +78,31 @@ func (_m *IPAM) ExecDel(_a0 string, netconf []byte) error {
 
 	return r0
 }
+
+// ExecGC provides a mock function with given fields: _a0, netconf
+func (_m *IPAM) ExecGC(_a0 string, netconf []byte) error {
+	ret := _m.Called(_a0, netconf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []byte) error); ok {
+		r0 = rf(_a0, netconf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExecStatus provides a mock function with given fields: _a0, netconf
+func (_m *IPAM) ExecStatus(_a0 string, netconf []byte) error {
+	ret := _m.Called(_a0, netconf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []byte) error); ok {
+		r0 = rf(_a0, netconf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
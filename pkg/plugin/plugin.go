@@ -10,6 +10,7 @@ import (
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -19,6 +20,23 @@ import (
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/config"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager"
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+const (
+	// errPluginNotAvailable is a well known CNI error code,
+	// returned by STATUS when the plugin is not able to handle ADD requests
+	errPluginNotAvailable uint = 50
+	// vfioDevInfoKey and uioDevInfoKey are keys of the userspace device path in pci field of DeviceInfo file
+	vfioDevInfoKey = "vfio-group"
+	uioDevInfoKey  = "uio-device"
+	// macDevInfoKey is a key of MAC of the VF with userspace driver in pci field of DeviceInfo file
+	macDevInfoKey = "mac"
+	// vdpaDevInfoKey is a key of vDPA device information in DeviceInfo file
+	vdpaDevInfoKey = "vdpa"
+	// vdpaDriverVhost and vdpaDriverVirtio are vDPA driver names used in DeviceInfo file
+	vdpaDriverVhost  = "vhost"
+	vdpaDriverVirtio = "virtio"
 )
 
 //nolint:gochecknoinits
@@ -41,6 +59,8 @@ func NewPlugin() *Plugin {
 		manager: manager.NewManager(),
 		config:  config.NewConfig(),
 		cache:   cache.NewStateCache(),
+
+		attachmentLock: newAttachmentLock,
 	}
 }
 
@@ -51,6 +71,8 @@ type Plugin struct {
 	manager manager.Manager
 	config  config.Loader
 	cache   cache.StateCache
+
+	attachmentLock func(ref cache.StateRef) manager.IPCLock
 }
 
 // CmdAdd implementation of accelerated-bridge-cni plugin
@@ -70,6 +92,10 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 	}
 	defer func() {
 		cmdCtx.handleError(err)
+		// the attachment lock is released after the clean up
+		if cmdCtx.unlock != nil {
+			cmdCtx.unlock()
+		}
 	}()
 
 	cmdCtx.registerErrorHandler(func() {
@@ -91,56 +117,51 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 		return fmt.Errorf("failed to open netns %q: %v", args.Netns, err)
 	}
 	defer cmdCtx.netNS.Close()
+	pluginConf.NetNSPath = args.Netns
+	pRef := p.cache.GetStateRef(pluginConf.Name, args.ContainerID, args.IfName)
+	pluginConf.AttachmentID = string(pRef)
+	if cmdCtx.unlock, err = p.lockAttachment(pRef); err != nil {
+		return err
+	}
 
-	cmdCtx.result.Interfaces = []*current.Interface{{
-		Name:    args.IfName,
-		Sandbox: cmdCtx.netNS.Path(),
-	}}
+	if err = initResult(cmdCtx); err != nil {
+		return err
+	}
 
 	err = p.getMACAddressConfig(cmdCtx)
 	if err != nil {
 		return fmt.Errorf("failed to get MAC config: %v", err)
 	}
 
-	if err = p.manager.AttachRepresentor(pluginConf); err != nil {
-		return fmt.Errorf("failed to attach representor: %v", err)
+	if err = p.setupHostSide(cmdCtx); err != nil {
+		return err
 	}
-	cmdCtx.registerErrorHandler(func() {
-		_ = p.manager.DetachRepresentor(pluginConf)
-	})
 
-	if err = p.manager.ApplyVFConfig(pluginConf); err != nil {
-		return fmt.Errorf("failed to configure VF %q", err)
+	macAddr, err := p.setupContainerIface(cmdCtx)
+	if err != nil {
+		return err
 	}
 
-	var macAddr string
-	if !pluginConf.IsUserspaceDriver {
-		macAddr, err = p.manager.SetupVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
-		cmdCtx.registerErrorHandler(func() {
-			netNSErr := cmdCtx.netNS.Do(func(_ ns.NetNS) error {
-				_, intErr := netlink.LinkByName(args.IfName)
-				return intErr
-			})
-			if netNSErr == nil {
-				_ = p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
-			}
-		})
+	if pluginConf.StaticFdb {
+		if err = p.manager.AddStaticFdb(pluginConf, macAddr); err != nil {
+			return fmt.Errorf("failed to add static FDB entries: %v", err)
+		}
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to set up pod interface %q from the device %q: %v",
-				args.IfName, pluginConf.PFName, err)
+	if pluginConf.WaitForCarrier {
+		if err = p.manager.WaitForCarrier(pluginConf, args.IfName, cmdCtx.netNS); err != nil {
+			return types.NewError(types.ErrTryAgainLater, err.Error(), "")
 		}
 	}
 
 	// run the IPAM plugin
 	if pluginConf.IPAM.Type != "" {
-		err = p.configureIPAM(cmdCtx, macAddr)
+		err = p.configureIPAM(cmdCtx)
 		if err != nil {
 			return fmt.Errorf("failed to configure IPAM: %v", err)
 		}
 	}
 	// Cache PluginConf for CmdDel
-	pRef := p.cache.GetStateRef(pluginConf.Name, args.ContainerID, args.IfName)
 	if err = p.cache.Save(pRef, pluginConf); err != nil {
 		return fmt.Errorf("failed to save PluginConf %q", err)
 	}
@@ -152,6 +173,105 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 	return types.PrintResult(cmdCtx.result, pluginConf.CNIVersion)
 }
 
+// initResult initializes the result with the container interface,
+// in a plugin chain, interfaces and IPs are appended to the previous result
+func initResult(cmdCtx *cmdContext) error {
+	if cmdCtx.pluginConf.PrevResult != nil {
+		result, err := current.NewResultFromResult(cmdCtx.pluginConf.PrevResult)
+		if err != nil {
+			return fmt.Errorf("failed to convert prevResult: %v", err)
+		}
+		cmdCtx.result = result
+	}
+
+	cmdCtx.contIfIndex = len(cmdCtx.result.Interfaces)
+	cmdCtx.result.Interfaces = append(cmdCtx.result.Interfaces, &current.Interface{
+		Name:    cmdCtx.args.IfName,
+		Sandbox: cmdCtx.netNS.Path(),
+	})
+	return nil
+}
+
+// setupHostSide checks that the VF can be used, binds the VF driver, attaches the representor to the bridge,
+// checks MAC conflicts and applies VF configuration through the PF
+func (p *Plugin) setupHostSide(cmdCtx *cmdContext) error {
+	pluginConf := cmdCtx.pluginConf
+	if err := p.manager.Preflight(pluginConf); err != nil {
+		return fmt.Errorf("preflight check failed: %v", err)
+	}
+
+	if err := p.manager.BindVFDriver(pluginConf); err != nil {
+		return fmt.Errorf("failed to bind VF driver: %v", err)
+	}
+	cmdCtx.registerErrorHandler(func() {
+		_ = p.manager.RestoreVFDriver(pluginConf)
+	})
+
+	if err := p.manager.AttachRepresentor(pluginConf); err != nil {
+		return fmt.Errorf("failed to attach representor: %v", err)
+	}
+	cmdCtx.registerErrorHandler(func() {
+		_ = p.manager.DetachRepresentor(pluginConf)
+	})
+
+	// report representor and the bridge as host-side interfaces
+	hostIfaces, err := p.manager.GetHostInterfaces(pluginConf)
+	if err != nil {
+		return fmt.Errorf("failed to get host interfaces: %v", err)
+	}
+	cmdCtx.result.Interfaces = append(cmdCtx.result.Interfaces, hostIfaces...)
+
+	if err = p.checkMACConflicts(pluginConf); err != nil {
+		return err
+	}
+
+	if err = p.manager.ApplyVFConfig(pluginConf); err != nil {
+		return fmt.Errorf("failed to configure VF %q", err)
+	}
+	return nil
+}
+
+// setupContainerIface moves the VF netdevice to the container or configures the VF with userspace driver,
+// returns MAC of the VF and updates the container interface in the result
+func (p *Plugin) setupContainerIface(cmdCtx *cmdContext) (string, error) {
+	pluginConf := cmdCtx.pluginConf
+	args := cmdCtx.args
+	contIface := cmdCtx.result.Interfaces[cmdCtx.contIfIndex]
+
+	if pluginConf.IsUserspaceDriver {
+		macAddr, err := p.manager.SetupUserspaceVF(pluginConf)
+		if err != nil {
+			return "", fmt.Errorf("failed to set up VF %q with userspace driver: %v", pluginConf.DeviceID, err)
+		}
+		contIface.Mac = macAddr
+		contIface.Mtu = pluginConf.UserspaceMTU
+		if pluginConf.VdpaPath != "" {
+			// vhost-vdpa device is consumed from userspace, report path to the character device
+			contIface.SocketPath = pluginConf.VdpaPath
+			contIface.PciID = pluginConf.DeviceID
+		}
+		return macAddr, nil
+	}
+
+	macAddr, err := p.manager.SetupVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
+	cmdCtx.registerErrorHandler(func() {
+		netNSErr := cmdCtx.netNS.Do(func(_ ns.NetNS) error {
+			_, intErr := netlink.LinkByName(args.IfName)
+			return intErr
+		})
+		if netNSErr == nil {
+			_ = p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
+		}
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to set up pod interface %q from the device %q: %v",
+			args.IfName, pluginConf.PFName, err)
+	}
+	contIface.Mac = macAddr
+	return macAddr, nil
+}
+
 // updateDeviceInfo updates CNIDeviceInfoFile file with information
 // about VF representor
 func (p *Plugin) updateDeviceInfo(cmdCtx *cmdContext) error {
@@ -198,22 +318,35 @@ func (p *Plugin) updateDeviceInfo(cmdCtx *cmdContext) error {
 		devInfo[versionKey] = minimalSpecVersion
 	}
 
+	updated := false
+	if cmdCtx.pluginConf.VdpaDriver != "" {
+		// vDPA device info may be provided by device plugin instead of pci device info
+		updated = updateVdpaDeviceInfo(devInfo, cmdCtx.pluginConf)
+	}
+
 	pciInfo, exist := devInfo[pciDevInfoKey]
-	if !exist {
+	switch {
+	case exist:
+		pciInfoMap, isMap := pciInfo.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("unexpected pci info format in CNIDeviceInfoFile")
+		}
+		if pciInfoMap[vfRepresentorNameKey] != cmdCtx.pluginConf.Representor {
+			pciInfoMap[vfRepresentorNameKey] = cmdCtx.pluginConf.Representor
+			updated = true
+		}
+		if updateUserspaceDeviceInfo(pciInfoMap, cmdCtx.pluginConf) {
+			updated = true
+		}
+		devInfo[pciDevInfoKey] = pciInfoMap
+	case cmdCtx.pluginConf.VdpaDriver == "":
 		return fmt.Errorf("pci field not found in CNIDeviceInfoFile")
 	}
-	pciInfoMap, ok := pciInfo.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("unexpected pci info format in CNIDeviceInfoFile")
-	}
-	currentValue := pciInfoMap[vfRepresentorNameKey]
-	if currentValue == cmdCtx.pluginConf.Representor {
+	if !updated {
 		// preserve existing value if already set
 		log.Debug().Msgf("representor-device already set, skip deviceInfo update")
 		return nil
 	}
-	pciInfoMap[vfRepresentorNameKey] = cmdCtx.pluginConf.Representor
-	devInfo[pciDevInfoKey] = pciInfoMap
 
 	bytes, err = json.Marshal(&devInfo)
 	if err != nil {
@@ -229,10 +362,66 @@ func (p *Plugin) updateDeviceInfo(cmdCtx *cmdContext) error {
 	return nil
 }
 
+// updateUserspaceDeviceInfo sets vfio group or uio device and MAC of the VF with userspace driver
+// in pci field of the DeviceInfo, returns true if the DeviceInfo was changed
+func updateUserspaceDeviceInfo(pciInfoMap map[string]interface{}, pluginConf *localtypes.PluginConf) bool {
+	expected := map[string]interface{}{}
+	if pluginConf.UserspaceDevice != "" {
+		key := uioDevInfoKey
+		if pluginConf.UserspaceDriver == utils.DriverVfioPci {
+			key = vfioDevInfoKey
+		}
+		expected[key] = pluginConf.UserspaceDevice
+	}
+	if pluginConf.UserspaceMAC != "" {
+		expected[macDevInfoKey] = pluginConf.UserspaceMAC
+	}
+	updated := false
+	for key, value := range expected {
+		if pciInfoMap[key] != value {
+			pciInfoMap[key] = value
+			updated = true
+		}
+	}
+	return updated
+}
+
+// updateVdpaDeviceInfo sets vDPA device information in vdpa field of the DeviceInfo,
+// returns true if the DeviceInfo was changed
+func updateVdpaDeviceInfo(devInfo map[string]interface{}, pluginConf *localtypes.PluginConf) bool {
+	vdpaInfoMap, ok := devInfo[vdpaDevInfoKey].(map[string]interface{})
+	if !ok {
+		vdpaInfoMap = map[string]interface{}{}
+	}
+	driver := vdpaDriverVirtio
+	if pluginConf.VdpaDriver == utils.VdpaDriverVhost {
+		driver = vdpaDriverVhost
+	}
+	expected := map[string]interface{}{
+		"parent-device":      pluginConf.VdpaDevice,
+		"driver":             driver,
+		"pci-address":        pluginConf.DeviceID,
+		"representor-device": pluginConf.Representor,
+	}
+	if pluginConf.VdpaPath != "" {
+		expected["path"] = pluginConf.VdpaPath
+	}
+	updated := false
+	for key, value := range expected {
+		if vdpaInfoMap[key] != value {
+			vdpaInfoMap[key] = value
+			updated = true
+		}
+	}
+	devInfo[vdpaDevInfoKey] = vdpaInfoMap
+	return updated
+}
+
 // mac address configuration can be supplied as
 // top-level configuration option in cni conf,
 // as env variable and in RuntimeConfig section in cni conf
 // priority: 1. runtime config 2. env 3. top-level option
+// MAC is generated if it is not supplied and macGenerate option is set
 func (p *Plugin) getMACAddressConfig(cmdCtx *cmdContext) error {
 	envArgs, err := getEnvArgs(cmdCtx.args.Args)
 	if err != nil {
@@ -251,11 +440,40 @@ func (p *Plugin) getMACAddressConfig(cmdCtx *cmdContext) error {
 	if pluginConf.RuntimeConfig.Mac != "" {
 		pluginConf.MAC = pluginConf.RuntimeConfig.Mac
 	}
+	if pluginConf.MAC == "" && pluginConf.MACGenerate {
+		pluginConf.MAC, err = generateMAC(cmdCtx, envArgs)
+		if err != nil {
+			return fmt.Errorf("failed to generate MAC: %v", err)
+		}
+		log.Info().Msgf("Generated MAC %s for interface %s", pluginConf.MAC, cmdCtx.args.IfName)
+	}
+	if pluginConf.MAC != "" {
+		return utils.ValidateMAC(pluginConf.MAC)
+	}
+	// MAC of VF with userspace driver is chosen by the application, static FDB entries can't be added for it
+	if pluginConf.StaticFdb && (pluginConf.IsUserspaceDriver || pluginConf.BindDriver != "") {
+		return fmt.Errorf("staticFdb option requires MAC for VF %s with userspace driver, "+
+			"set it with mac, runtimeConfig mac, MAC in CNI_ARGS or macGenerate option", pluginConf.DeviceID)
+	}
 	return nil
 }
 
+// generateMAC returns stable MAC which depends on the network name, the pod and the interface name,
+// container ID is used to identify the pod if the pod namespace and name are not provided in args
+func generateMAC(cmdCtx *cmdContext, envArgs *envArgs) (string, error) {
+	pod := cmdCtx.args.ContainerID
+	if envArgs != nil && envArgs.K8S_POD_NAMESPACE != "" && envArgs.K8S_POD_NAME != "" {
+		pod = string(envArgs.K8S_POD_NAMESPACE) + "/" + string(envArgs.K8S_POD_NAME)
+	}
+	prefix := cmdCtx.pluginConf.MACPrefix
+	if prefix == "" {
+		prefix = utils.DefaultMACPrefix
+	}
+	return utils.GenerateMAC(prefix, cmdCtx.pluginConf.Name, pod, cmdCtx.args.IfName)
+}
+
 // call ipam plugin
-func (p *Plugin) configureIPAM(cmdCtx *cmdContext, macAddr string) error {
+func (p *Plugin) configureIPAM(cmdCtx *cmdContext) error {
 	var ipamResult types.Result
 	var err error
 
@@ -283,25 +501,46 @@ func (p *Plugin) configureIPAM(cmdCtx *cmdContext, macAddr string) error {
 	}
 
 	newResult.Interfaces = cmdCtx.result.Interfaces
-	newResult.Interfaces[0].Mac = macAddr
 
 	for _, ipc := range newResult.IPs {
 		// All addresses apply to the container interface (move from host)
-		ipc.Interface = current.Int(0)
+		ipc.Interface = current.Int(cmdCtx.contIfIndex)
 	}
 
 	if !pluginConf.IsUserspaceDriver {
 		err = cmdCtx.netNS.Do(func(_ ns.NetNS) error {
-			return p.ipam.ConfigureIface(args.IfName, newResult)
+			if ipamErr := p.ipam.ConfigureIface(args.IfName, newResult); ipamErr != nil {
+				return ipamErr
+			}
+			// let the bridge, upstream switches and neighbors learn the pod before the first packet from it
+			if pluginConf.AnnounceCount > 0 || pluginConf.WaitForDAD {
+				return p.manager.AnnounceAddresses(pluginConf, args.IfName, newResult.IPs)
+			}
+			return nil
 		})
 		if err != nil {
 			return err
 		}
 	}
-	cmdCtx.result = newResult
+
+	// merge IPAM result with the previous result
+	cmdCtx.result.IPs = append(cmdCtx.result.IPs, newResult.IPs...)
+	cmdCtx.result.Routes = append(cmdCtx.result.Routes, newResult.Routes...)
+	mergeDNS(&cmdCtx.result.DNS, &newResult.DNS)
 	return nil
 }
 
+// mergeDNS appends DNS configuration from src to dst,
+// domain from src is used only if dst has no domain
+func mergeDNS(dst, src *types.DNS) {
+	if dst.Domain == "" {
+		dst.Domain = src.Domain
+	}
+	dst.Nameservers = append(dst.Nameservers, src.Nameservers...)
+	dst.Search = append(dst.Search, src.Search...)
+	dst.Options = append(dst.Options, src.Options...)
+}
+
 // CmdDel implementation of accelerated-bridge-cni plugin
 func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 	// https://github.com/kubernetes/kubernetes/pull/35240
@@ -327,8 +566,14 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 
 	pRef := p.cache.GetStateRef(netConf.Name, args.ContainerID, args.IfName)
 
+	var unlock func()
+	if unlock, err = p.lockAttachment(pRef); err != nil {
+		return err
+	}
+	defer unlock()
+
 	pluginConf := &localtypes.PluginConf{}
-	err = p.cache.Load(pRef, pluginConf)
+	err = p.loadAttachment(pRef, pluginConf)
 	if err != nil {
 		// If cmdDel() fails, cached netconf is cleaned up by
 		// the followed defer call or might not exist in the first place.
@@ -345,8 +590,9 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 		setDebugMode()
 	}
 
+	keepCache := false
 	defer func() {
-		if err == nil {
+		if err == nil && !keepCache {
 			_ = p.cache.Delete(pRef)
 		}
 	}()
@@ -371,6 +617,13 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 		// IPAM resources
 		_, ok := err.(ns.NSPathNotExistErr)
 		if ok {
+			// VF is moved back to init netns when netns is destroyed,
+			// keep cached state if VF can't be restored now, it will be restored by reconcile command
+			if releaseErr := p.restoreStaleVF(pluginConf); releaseErr != nil {
+				log.Warn().Msgf("failed to restore VF, keep cached state for reconcile: %v", releaseErr)
+				keepCache = true
+			}
+			err = nil
 			return nil
 		}
 
@@ -390,10 +643,250 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 		return fmt.Errorf("cmdDel() error reseting VF: %q", err)
 	}
 
+	//nolint:gocritic
+	if err = p.manager.RestoreVFDriver(pluginConf); err != nil {
+		return fmt.Errorf("cmdDel() error restoring VF driver: %q", err)
+	}
+
 	return nil
 }
 
 // CmdCheck implementation of accelerated-bridge-cni plugin
 func (p *Plugin) CmdCheck(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdCheck done.")
+		} else {
+			log.Error().Msgf("CmdCheck failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	err = p.config.LoadConf(args.StdinData, netConf)
+	if err != nil {
+		return err
+	}
+
+	if netConf.Debug {
+		setDebugMode()
+	}
+
+	if netConf.RawPrevResult == nil {
+		err = fmt.Errorf("required prevResult missing")
+		return err
+	}
+	if err = version.ParsePrevResult(&netConf.NetConf); err != nil {
+		return fmt.Errorf("failed to parse prevResult: %v", err)
+	}
+	var result *current.Result
+	result, err = current.NewResultFromResult(netConf.PrevResult)
+	if err != nil {
+		return fmt.Errorf("failed to convert prevResult: %v", err)
+	}
+
+	pRef := p.cache.GetStateRef(netConf.Name, args.ContainerID, args.IfName)
+	pluginConf := &localtypes.PluginConf{}
+	if err = p.loadAttachment(pRef, pluginConf); err != nil {
+		return fmt.Errorf("failed to load cached state: %v", err)
+	}
+
+	if pluginConf.IPAM.Type != "" {
+		if err = p.ipam.ExecCheck(pluginConf.IPAM.Type, args.StdinData); err != nil {
+			return err
+		}
+	}
+
+	if err = p.manager.CheckRepresentor(pluginConf); err != nil {
+		return err
+	}
+
+	if pluginConf.IsUserspaceDriver {
+		return nil
+	}
+
+	contIface, ips, err := getContainerIfaceResult(result, args.IfName)
+	if err != nil {
+		return err
+	}
+
+	netns, err := p.netNS.GetNS(args.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %v", args.Netns, err)
+	}
+	defer netns.Close()
+
+	if err = p.manager.CheckVF(pluginConf, contIface, ips, netns); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getContainerIfaceResult returns interface with ifName from the container sandbox
+// and IPs assigned to this interface
+func getContainerIfaceResult(result *current.Result, ifName string) (
+	*current.Interface, []*current.IPConfig, error) {
+	ifIndex := -1
+	for i, iface := range result.Interfaces {
+		if iface.Name == ifName && iface.Sandbox != "" {
+			ifIndex = i
+			break
+		}
+	}
+	if ifIndex < 0 {
+		return nil, nil, fmt.Errorf("interface %s not found in prevResult", ifName)
+	}
+	var ips []*current.IPConfig
+	for _, ipc := range result.IPs {
+		if ipc.Interface != nil && *ipc.Interface == ifIndex {
+			ips = append(ips, ipc)
+		}
+	}
+	return result.Interfaces[ifIndex], ips, nil
+}
+
+// CmdGC implementation of accelerated-bridge-cni plugin,
+// releases all cached attachments of the network which are not in the list of valid attachments
+func (p *Plugin) CmdGC(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdGC done.")
+		} else {
+			log.Error().Msgf("CmdGC failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	err = p.config.LoadConf(args.StdinData, netConf)
+	if err != nil {
+		return err
+	}
+
+	if netConf.Debug {
+		setDebugMode()
+	}
+
+	validRefs := make(map[cache.StateRef]bool, len(netConf.ValidAttachments))
+	for _, a := range netConf.ValidAttachments {
+		validRefs[p.cache.GetStateRef(netConf.Name, a.ContainerID, a.IfName)] = true
+	}
+
+	var refs []cache.StateRef
+	refs, err = p.cache.List(netConf.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list cached attachments: %v", err)
+	}
+
+	var errs []error
+	for _, ref := range refs {
+		if validRefs[ref] {
+			continue
+		}
+		if gcErr := p.gcAttachment(ref, netConf.Name); gcErr != nil {
+			errs = append(errs, gcErr)
+		}
+	}
+
+	if netConf.IPAM.Type != "" {
+		if ipamErr := p.ipam.ExecGC(netConf.IPAM.Type, args.StdinData); ipamErr != nil {
+			errs = append(errs, ipamErr)
+		}
+	}
+
+	err = errors.Join(errs...)
+	return err
+}
+
+// gcAttachment releases the cached attachment of the network, the attachment is locked
+// and loaded under the lock to not race with DEL of the same attachment
+func (p *Plugin) gcAttachment(ref cache.StateRef, network string) error {
+	unlock, err := p.lockAttachment(ref)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pluginConf := &localtypes.PluginConf{}
+	if loadErr := p.loadAttachment(ref, pluginConf); loadErr != nil {
+		log.Warn().Msgf("failed to load cached attachment %s, skip it: %v", ref, loadErr)
+		return nil
+	}
+	// cache reference is prefixed with the network name,
+	// skip attachments of other networks which share the same prefix
+	if pluginConf.Name != network {
+		return nil
+	}
+	log.Info().Msgf("Releasing stale attachment %s", ref)
+	if err = p.releaseAttachment(pluginConf); err != nil {
+		return fmt.Errorf("failed to release attachment %s: %v", ref, err)
+	}
+	return p.cache.Delete(ref)
+}
+
+// loadAttachment loads cached PluginConf of the attachment, attachments cached before
+// the uplink VLAN ledger was introduced have no AttachmentID, the cache reference is used instead
+func (p *Plugin) loadAttachment(ref cache.StateRef, pluginConf *localtypes.PluginConf) error {
+	if err := p.cache.Load(ref, pluginConf); err != nil {
+		return err
+	}
+	if pluginConf.AttachmentID == "" {
+		pluginConf.AttachmentID = string(ref)
+	}
+	return nil
+}
+
+// releaseAttachment detaches representor and resets VF configuration
+// for the attachment which was not released with CmdDel
+func (p *Plugin) releaseAttachment(pluginConf *localtypes.PluginConf) error {
+	if err := p.manager.DetachRepresentor(pluginConf); err != nil {
+		log.Warn().Msgf("failed to detach representor: %v", err)
+	}
+	if err := p.manager.ResetVFConfig(pluginConf); err != nil {
+		return fmt.Errorf("error reseting VF: %q", err)
+	}
+	return p.manager.RestoreVFDriver(pluginConf)
+}
+
+// CmdStatus implementation of accelerated-bridge-cni plugin,
+// reports whether the plugin is able to handle ADD requests
+func (p *Plugin) CmdStatus(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdStatus done.")
+		} else {
+			log.Error().Msgf("CmdStatus failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	err = p.config.LoadConf(args.StdinData, netConf)
+	if err != nil {
+		return err
+	}
+
+	var bridges []string
+	bridges, err = config.GetBridgeNames(netConf.Bridge)
+	if err != nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, err.Error(), "")
+	}
+
+	// bridge is created on ADD if it doesn't exist
+	if netConf.CreateBridge {
+		bridges = nil
+	}
+
+	if err = p.manager.CheckStatus(bridges); err != nil {
+		return types.NewError(errPluginNotAvailable, "plugin is not available", err.Error())
+	}
+
+	if netConf.IPAM.Type != "" {
+		err = p.ipam.ExecStatus(netConf.IPAM.Type, args.StdinData)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net"
@@ -15,9 +16,11 @@ import (
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
 	cacheMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache/mocks"
 	configMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/config/mocks"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager"
 	managerMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager/mocks"
 	pluginMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/plugin/mocks"
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -43,9 +46,9 @@ var (
 	testValidContIFNames                = "net1"
 	testValidContainerID                = "a1b2c3d4e5f6"
 	testValidNSPath                     = "/proc/12444/ns/net"
-	testValidMAC                        = "b3:ec:90:4c:5b:11"
-	testValidMAC2                       = "b3:ec:90:4c:5b:12"
-	testValidMAC3                       = "b3:ec:90:4c:5b:13"
+	testValidMAC                        = "b2:ec:90:4c:5b:11"
+	testValidMAC2                       = "b2:ec:90:4c:5b:12"
+	testValidMAC3                       = "b2:ec:90:4c:5b:13"
 	testValidCacheRef    cache.StateRef = "/var/lib/cni/accelerated-bridge/mynet-a1b2c3d4e5f6-net1"
 	errTest                             = errors.New("test err")
 )
@@ -64,6 +67,8 @@ func getValidPluginConf() *localtypes.PluginConf {
 		PFName:       testValidPFName,
 		ActualBridge: testValidBridge,
 		ContIFNames:  testValidContIFNames,
+		NetNSPath:    testValidNSPath,
+		AttachmentID: string(testValidCacheRef),
 		VFID:         testValidVFID,
 		Representor:  testValidRepName,
 	}
@@ -90,6 +95,13 @@ func getValidIPAMResult() types.Result {
 	}
 }
 
+func getValidHostInterfaces() []*current.Interface {
+	return []*current.Interface{
+		{Name: testValidRepName, Mac: testValidMAC2},
+		{Name: testValidBridge, Mac: testValidMAC3},
+	}
+}
+
 var _ = Describe("Plugin - test CNI command flows", func() {
 	var (
 		t           GinkgoTInterface
@@ -100,6 +112,8 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		managerMock *managerMocks.Manager
 		configMock  *configMocks.Loader
 		netNSMock   *pluginMocks.NetNS
+		lockMock    *managerMocks.IPCLock
+		lockedRefs  []cache.StateRef
 		pluginConf  *localtypes.PluginConf
 		cmdArgs     *skel.CmdArgs
 	)
@@ -112,12 +126,21 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		managerMock = &managerMocks.Manager{}
 		configMock = &configMocks.Loader{}
 		netNSMock = &pluginMocks.NetNS{}
+		lockMock = &managerMocks.IPCLock{}
+		lockMock.On("Lock").Return(nil).Maybe()
+		lockMock.On("Unlock").Return(nil).Maybe()
+		lockedRefs = nil
 		plugin = Plugin{
 			netNS:   nsMock,
 			ipam:    ipamMock,
 			manager: managerMock,
 			config:  configMock,
 			cache:   cacheMock,
+
+			attachmentLock: func(ref cache.StateRef) manager.IPCLock {
+				lockedRefs = append(lockedRefs, ref)
+				return lockMock
+			},
 		}
 		pluginConf = getValidPluginConf()
 		cmdArgs = getValidCmdArgs()
@@ -144,12 +167,17 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}
 			nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
 			netNSMock.On("Path").Return(testValidNSPath).Once()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(testValidCacheRef).Once()
+			managerMock.On("Preflight", mock.Anything).Return(nil).Once()
+			managerMock.On("BindVFDriver", mock.Anything).Return(nil).Once()
 		}
 		successfullyAttachRepresentor := func(withDeps bool) {
 			if withDeps {
 				successfullyGetNS(true)
 			}
 			managerMock.On("AttachRepresentor", pluginConf).Return(nil).Once()
+			managerMock.On("GetHostInterfaces", pluginConf).Return(getValidHostInterfaces(), nil).Once()
 		}
 		successfullyApplyVFConfig := func(withDeps bool) {
 			if withDeps {
@@ -186,8 +214,6 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}).Once()
 		}
 		configureCacheMock := func() {
-			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
-				Return(testValidCacheRef).Once()
 			cacheMock.On("Save", testValidCacheRef, pluginConf).
 				Return(nil).Once()
 		}
@@ -200,8 +226,12 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		cleanupGetNS := func() {
 			netNSMock.On("Close").Return(nil).Once()
 		}
-		cleanupAttachRepresentor := func() {
+		cleanupBindVFDriver := func() {
 			cleanupGetNS()
+			managerMock.On("RestoreVFDriver", mock.Anything).Return(nil).Once()
+		}
+		cleanupAttachRepresentor := func() {
+			cleanupBindVFDriver()
 			managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
 		}
 		cleanupSetupVFConfig := func() {
@@ -224,10 +254,54 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				nsMock.On("GetNS", testValidNSPath).Return(nil, errTest).Once()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to lock attachment", func() {
+				successfullyParseConfig(true)
+				nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				lockMock.ExpectedCalls = nil
+				lockMock.On("Lock").Return(errTest).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+				Expect(lockedRefs).To(Equal([]cache.StateRef{testValidCacheRef}))
+				managerMock.AssertNotCalled(t, "Preflight", mock.Anything)
+				lockMock.AssertNotCalled(t, "Unlock")
+			})
+			It("Failed preflight check", func() {
+				successfullyParseConfig(true)
+				nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
+				netNSMock.On("Path").Return(testValidNSPath).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				managerMock.On("Preflight", pluginConf).Return(errTest).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+				managerMock.AssertNotCalled(GinkgoT(), "AttachRepresentor", pluginConf)
+			})
+			It("Failed to bind VF driver", func() {
+				successfullyParseConfig(true)
+				nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
+				netNSMock.On("Path").Return(testValidNSPath).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				managerMock.On("Preflight", pluginConf).Return(nil).Once()
+				managerMock.On("BindVFDriver", pluginConf).Return(errTest).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+				managerMock.AssertNotCalled(GinkgoT(), "AttachRepresentor", pluginConf)
+				managerMock.AssertNotCalled(GinkgoT(), "RestoreVFDriver", pluginConf)
+			})
 			It("Failed to attach representor", func() {
 				successfullyGetNS(true)
 				managerMock.On("AttachRepresentor", pluginConf).Return(errTest).Once()
-				cleanupGetNS()
+				cleanupBindVFDriver()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to get host interfaces", func() {
+				successfullyGetNS(true)
+				managerMock.On("AttachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("GetHostInterfaces", pluginConf).Return(nil, errTest).Once()
+				cleanupAttachRepresentor()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
 			It("Failed to ApplyVFConfig", func() {
@@ -244,6 +318,13 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupSetupVFConfig()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to set up VF with userspace driver", func() {
+				pluginConf.IsUserspaceDriver = true
+				successfullyApplyVFConfig(true)
+				managerMock.On("SetupUserspaceVF", pluginConf).Return("", errTest).Once()
+				cleanupAttachRepresentor()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
 			It("Failed to IPAM Add", func() {
 				successfullySetupVF(true)
 				ipamMock.On("ExecAdd", pluginConf.IPAM.Type, cmdArgs.StdinData).
@@ -270,10 +351,33 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupExecAdd()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to wait for DAD", func() {
+				pluginConf.WaitForDAD = true
+				successfullyConfigureIface(true)
+				managerMock.On("AnnounceAddresses", pluginConf, cmdArgs.IfName, mock.Anything).
+					Return(errTest).Once()
+				cleanupExecAdd()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to add static FDB", func() {
+				pluginConf.StaticFdb = true
+				successfullySetupVF(true)
+				managerMock.On("AddStaticFdb", pluginConf, testValidMAC).Return(errTest).Once()
+				cleanupSetupVFConfig()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to wait for carrier", func() {
+				pluginConf.WaitForCarrier = true
+				successfullySetupVF(true)
+				managerMock.On("WaitForCarrier", pluginConf, cmdArgs.IfName, netNSMock).
+					Return(errTest).Once()
+				cleanupSetupVFConfig()
+				err := plugin.CmdAdd(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(types.ErrTryAgainLater))
+			})
 			It("Failed save cache", func() {
 				successfullyConfigureIface(true)
-				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
-					Return(testValidCacheRef).Once()
 				cacheMock.On("Save", testValidCacheRef, pluginConf).
 					Return(errTest).Once()
 				cleanupExecAdd()
@@ -286,6 +390,26 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
 			})
+			It("with IPAM and address announcements", func() {
+				pluginConf.AnnounceCount = 3
+				successfullySave(true)
+				managerMock.On("AnnounceAddresses", pluginConf, cmdArgs.IfName,
+					mock.MatchedBy(func(ips []*current.IPConfig) bool {
+						return len(ips) == 1 && ips[0].Address.String() == "192.168.100.0/24"
+					})).Return(nil).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("wait for carrier", func() {
+				pluginConf.WaitForCarrier = true
+				successfullySetupVF(true)
+				managerMock.On("WaitForCarrier", pluginConf, cmdArgs.IfName, netNSMock).Return(nil).Once()
+				successfullyExecAdd(false)
+				successfullyConfigureIface(false)
+				successfullySave(false)
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
 			It("no IPAM", func() {
 				pluginConf.IPAM = types.IPAM{}
 				successfullySetupVF(true)
@@ -293,9 +417,57 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
 			})
+			It("chained plugin with prevResult", func() {
+				_, prevIPNet, _ := net.ParseCIDR("10.0.0.2/24")
+				pluginConf.PrevResult = &current.Result{
+					CNIVersion: "1.0.0",
+					Interfaces: []*current.Interface{{Name: "eth0", Sandbox: testValidNSPath}},
+					IPs:        []*current.IPConfig{{Address: *prevIPNet, Interface: current.Int(0)}},
+				}
+				successfullyExecAdd(true)
+				ipamMock.On("ConfigureIface", cmdArgs.IfName,
+					mock.MatchedBy(func(conf *current.Result) bool {
+						return len(conf.Interfaces) == 4 && conf.Interfaces[1].Name == testValidContIFNames &&
+							conf.Interfaces[1].Mac == testValidMAC &&
+							len(conf.IPs) == 1 && *conf.IPs[0].Interface == 1
+					})).
+					Return(nil).Once()
+				netNSMock.On("Do", mock.Anything).Return(func(f func(ns.NetNS) error) error {
+					return f(nil)
+				}).Once()
+				configureCacheMock()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("static FDB", func() {
+				pluginConf.StaticFdb = true
+				successfullySetupVF(true)
+				managerMock.On("AddStaticFdb", pluginConf, testValidMAC).Return(nil).Once()
+				successfullyExecAdd(false)
+				successfullyConfigureIface(false)
+				successfullySave(false)
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("static FDB with userspace driver", func() {
+				pluginConf.StaticFdb = true
+				pluginConf.IsUserspaceDriver = true
+				pluginConf.MAC = testValidMAC2
+				successfullyAttachRepresentor(true)
+				managerMock.On("FindFdbMACConflicts", pluginConf).Return(nil, nil).Once()
+				cacheMock.On("List", "").Return(nil, nil).Once()
+				successfullyApplyVFConfig(false)
+				managerMock.On("SetupUserspaceVF", pluginConf).Return(testValidMAC2, nil).Once()
+				managerMock.On("AddStaticFdb", pluginConf, testValidMAC2).Return(nil).Once()
+				successfullyExecAdd(false)
+				successfullySave(false)
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
 			It("userspace driver", func() {
 				pluginConf.IsUserspaceDriver = true
 				successfullyApplyVFConfig(true)
+				managerMock.On("SetupUserspaceVF", pluginConf).Return(testValidMAC, nil).Once()
 				successfullyExecAdd(false)
 				successfullySave(false)
 				cleanupGetNS()
@@ -308,7 +480,7 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 
 			JustBeforeEach(func() {
 				successfullyGetNS(true)
-				cleanupGetNS()
+				cleanupBindVFDriver()
 				// workaround to access pluginConf
 				managerMock.On("AttachRepresentor", mock.Anything).Run(func(args mock.Arguments) {
 					updatedPluginConf = args[0].(*localtypes.PluginConf)
@@ -336,6 +508,121 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				_ = plugin.CmdAdd(cmdArgs)
 				Expect(updatedPluginConf.MAC).To(BeIdenticalTo(testValidMAC3))
 			})
+			It("MAC is generated from pod name", func() {
+				pluginConf.MACGenerate = true
+				cmdArgs.Args = "IgnoreUnknown=1;K8S_POD_NAMESPACE=default;K8S_POD_NAME=vm1"
+				_ = plugin.CmdAdd(cmdArgs)
+				expected, err := utils.GenerateMAC(utils.DefaultMACPrefix, testValidName, "default/vm1",
+					testValidContIFNames)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedPluginConf.MAC).To(Equal(expected))
+			})
+			It("MAC is generated from container ID with configured prefix", func() {
+				pluginConf.MACGenerate = true
+				pluginConf.MACPrefix = "0a:58:0a"
+				_ = plugin.CmdAdd(cmdArgs)
+				Expect(updatedPluginConf.MAC).To(HavePrefix("0a:58:0a:"))
+				expected, err := utils.GenerateMAC("0a:58:0a", testValidName, testValidContainerID,
+					testValidContIFNames)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedPluginConf.MAC).To(Equal(expected))
+			})
+			It("multicast MAC from env is rejected", func() {
+				cmdArgs.Args = "MAC=01:00:5e:00:00:01"
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+				managerMock.AssertNotCalled(t, "Preflight", mock.Anything)
+				// expectations registered for the flow are not used
+				managerMock.ExpectedCalls = nil
+				netNSMock.ExpectedCalls = nil
+			})
+			It("static FDB with userspace driver without MAC is rejected", func() {
+				pluginConf.StaticFdb = true
+				pluginConf.IsUserspaceDriver = true
+				err := plugin.CmdAdd(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("staticFdb option requires MAC"))
+				managerMock.AssertNotCalled(t, "Preflight", mock.Anything)
+				// expectations registered for the flow are not used
+				managerMock.ExpectedCalls = nil
+				netNSMock.ExpectedCalls = nil
+			})
+			It("static FDB with driver binding uses generated MAC", func() {
+				pluginConf.StaticFdb = true
+				pluginConf.BindDriver = "vfio-pci"
+				pluginConf.MACGenerate = true
+				_ = plugin.CmdAdd(cmdArgs)
+				Expect(updatedPluginConf.MAC).NotTo(BeEmpty())
+			})
+			It("configured MAC has higher priority than generated MAC", func() {
+				pluginConf.MACGenerate = true
+				pluginConf.RuntimeConfig.Mac = testValidMAC3
+				_ = plugin.CmdAdd(cmdArgs)
+				Expect(updatedPluginConf.MAC).To(BeIdenticalTo(testValidMAC3))
+			})
+		})
+
+		Context("MAC conflicts", func() {
+			var (
+				cachedConf *localtypes.PluginConf
+				cachedRef  cache.StateRef = "other-net-c1d2-net1"
+			)
+			JustBeforeEach(func() {
+				pluginConf.MAC = testValidMAC
+				cachedConf = getValidPluginConf()
+				cachedConf.Name = "other-net"
+				cachedConf.AttachmentID = string(cachedRef)
+				cachedConf.MAC = testValidMAC
+			})
+			successfullyListCache := func() {
+				cacheMock.On("List", "").Return([]cache.StateRef{testValidCacheRef, cachedRef}, nil).Once()
+				cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = *pluginConf
+				}).Return(nil).Once()
+				cacheMock.On("Load", cachedRef, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = *cachedConf
+				}).Return(nil).Once()
+			}
+			It("no conflicts", func() {
+				cachedConf.MAC = testValidMAC2
+				managerMock.On("FindFdbMACConflicts", pluginConf).Return(nil, nil).Once()
+				successfullyListCache()
+				Expect(plugin.checkMACConflicts(pluginConf)).NotTo(HaveOccurred())
+			})
+			It("conflict with cached attachment, warn policy", func() {
+				managerMock.On("FindFdbMACConflicts", pluginConf).Return(nil, nil).Once()
+				successfullyListCache()
+				Expect(plugin.checkMACConflicts(pluginConf)).NotTo(HaveOccurred())
+			})
+			It("conflict with cached attachment, fail policy", func() {
+				pluginConf.MACConflictPolicy = "fail"
+				managerMock.On("FindFdbMACConflicts", pluginConf).Return(nil, nil).Once()
+				successfullyListCache()
+				err := plugin.checkMACConflicts(pluginConf)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(string(cachedRef)))
+			})
+			It("cached attachment with the same MAC on other VLANs", func() {
+				pluginConf.MACConflictPolicy = "fail"
+				cachedConf.Vlan = 200
+				cachedConf.Trunk = nil
+				managerMock.On("FindFdbMACConflicts", pluginConf).Return(nil, nil).Once()
+				successfullyListCache()
+				Expect(plugin.checkMACConflicts(pluginConf)).NotTo(HaveOccurred())
+			})
+			It("conflict in FDB, fail policy", func() {
+				pluginConf.MACConflictPolicy = "fail"
+				cachedConf.MAC = testValidMAC2
+				managerMock.On("FindFdbMACConflicts", pluginConf).
+					Return([]string{"FDB entry on port pf0vf3 vlan 100"}, nil).Once()
+				successfullyListCache()
+				err := plugin.checkMACConflicts(pluginConf)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("pf0vf3"))
+			})
+			It("ignore policy", func() {
+				pluginConf.MACConflictPolicy = "ignore"
+				Expect(plugin.checkMACConflicts(pluginConf)).NotTo(HaveOccurred())
+			})
 		})
 
 		Context("Update DeviceInfo", func() {
@@ -394,6 +681,76 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				})
 			})
 
+			Context("VF with userspace driver", func() {
+				var (
+					cmdCtx *cmdContext
+				)
+				BeforeEach(func() {
+					cmdCtx = &cmdContext{pluginConf: &localtypes.PluginConf{}}
+					cmdCtx.pluginConf.Representor = "eth3"
+					cmdCtx.pluginConf.IsUserspaceDriver = true
+					cmdCtx.pluginConf.RuntimeConfig.CNIDeviceInfoFile = tmpFile
+					Expect(os.WriteFile(tmpFile,
+						[]byte(`{"version": "1.1.0", "pci": {"pci-address": "0000:d8:00.2"}}`), 0600)).NotTo(HaveOccurred())
+				})
+				It("add vfio group", func() {
+					cmdCtx.pluginConf.UserspaceDriver = "vfio-pci"
+					cmdCtx.pluginConf.UserspaceDevice = "/dev/vfio/42"
+					Expect(plugin.updateDeviceInfo(cmdCtx)).NotTo(HaveOccurred())
+					result, err := os.ReadFile(tmpFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(`{"version": "1.1.0", "pci": {"pci-address": "0000:d8:00.2",
+						"representor-device": "eth3", "vfio-group": "/dev/vfio/42"}}`))
+				})
+				It("add uio device and MAC", func() {
+					cmdCtx.pluginConf.UserspaceDriver = "igb_uio"
+					cmdCtx.pluginConf.UserspaceDevice = "/dev/uio0"
+					cmdCtx.pluginConf.UserspaceMAC = testValidMAC
+					Expect(plugin.updateDeviceInfo(cmdCtx)).NotTo(HaveOccurred())
+					result, err := os.ReadFile(tmpFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(`{"version": "1.1.0", "pci": {"pci-address": "0000:d8:00.2",
+						"representor-device": "eth3", "uio-device": "/dev/uio0", "mac": "b2:ec:90:4c:5b:11"}}`))
+				})
+			})
+
+			Context("vDPA device", func() {
+				var (
+					cmdCtx *cmdContext
+				)
+				BeforeEach(func() {
+					cmdCtx = &cmdContext{pluginConf: &localtypes.PluginConf{}}
+					cmdCtx.pluginConf.Representor = "eth3"
+					cmdCtx.pluginConf.DeviceID = "0000:d8:00.2"
+					cmdCtx.pluginConf.VdpaDevice = "vdpa0"
+					cmdCtx.pluginConf.VdpaDriver = "vhost_vdpa"
+					cmdCtx.pluginConf.VdpaPath = "/dev/vhost-vdpa-0"
+					cmdCtx.pluginConf.RuntimeConfig.CNIDeviceInfoFile = tmpFile
+				})
+				It("add vdpa info to pci DeviceInfo", func() {
+					Expect(os.WriteFile(tmpFile,
+						[]byte(`{"version": "1.1.0", "pci": {"pci-address": "0000:d8:00.2"}}`), 0600)).NotTo(HaveOccurred())
+					Expect(plugin.updateDeviceInfo(cmdCtx)).NotTo(HaveOccurred())
+					result, err := os.ReadFile(tmpFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(`{"version": "1.1.0",
+						"pci": {"pci-address": "0000:d8:00.2", "representor-device": "eth3"},
+						"vdpa": {"parent-device": "vdpa0", "driver": "vhost", "path": "/dev/vhost-vdpa-0",
+							"pci-address": "0000:d8:00.2", "representor-device": "eth3"}}`))
+				})
+				It("merge vdpa DeviceInfo", func() {
+					Expect(os.WriteFile(tmpFile,
+						[]byte(`{"version": "1.1.0", "type": "vdpa", "vdpa": {"parent-device": "vdpa0", "driver": "vhost"}}`),
+						0600)).NotTo(HaveOccurred())
+					Expect(plugin.updateDeviceInfo(cmdCtx)).NotTo(HaveOccurred())
+					result, err := os.ReadFile(tmpFile)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(`{"version": "1.1.0", "type": "vdpa",
+						"vdpa": {"parent-device": "vdpa0", "driver": "vhost", "path": "/dev/vhost-vdpa-0",
+							"pci-address": "0000:d8:00.2", "representor-device": "eth3"}}`))
+				})
+			})
+
 			It("no CNIDeviceInfoFile option", func() {
 				cmdCtx := &cmdContext{pluginConf: &localtypes.PluginConf{}}
 				Expect(plugin.updateDeviceInfo(cmdCtx)).NotTo(HaveOccurred())
@@ -460,6 +817,8 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			successfullyReleaseVF()
 			managerMock.On("ResetVFConfig", pluginConf).
 				Return(nil)
+			managerMock.On("RestoreVFDriver", pluginConf).
+				Return(nil)
 		}
 
 		cleanupClose := func() {
@@ -485,6 +844,15 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 					Return(errTest).Once()
 				Expect(plugin.CmdDel(cmdArgs)).ToNot(HaveOccurred())
 			})
+			It("Failed to lock attachment", func() {
+				successfullyLoadConfig()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				lockMock.ExpectedCalls = nil
+				lockMock.On("Lock").Return(errTest).Once()
+				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
+				cacheMock.AssertNotCalled(t, "Load", testValidCacheRef, mock.Anything)
+			})
 			It("Failed to call IPAM del", func() {
 				successfullyDetachRepresentor()
 				ipamMock.On("ExecDel", pluginConf.IPAM.Type, cmdArgs.StdinData).
@@ -505,6 +873,15 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupClose()
 				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to restore VF driver", func() {
+				successfullyReleaseVF()
+				managerMock.On("ResetVFConfig", pluginConf).
+					Return(nil)
+				managerMock.On("RestoreVFDriver", pluginConf).
+					Return(errTest)
+				cleanupClose()
+				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
+			})
 		})
 		Context("Successful scenarios", func() {
 			It("no NetNs provided", func() {
@@ -514,6 +891,16 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			It("Failed to get NS, should return no error", func() {
 				successfullyExecDel()
 				nsMock.On("GetNS", cmdArgs.Netns).Return(nil, ns.NSPathNotExistErr{}).Once()
+				managerMock.On("RestoreVF", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", testValidCacheRef).Return(nil).Once()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("Failed to get NS and restore VF, should keep cache and return no error", func() {
+				successfullyExecDel()
+				nsMock.On("GetNS", cmdArgs.Netns).Return(nil, ns.NSPathNotExistErr{}).Once()
+				managerMock.On("RestoreVF", pluginConf).Return(errTest).Once()
 				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
 			})
 			It("success", func() {
@@ -521,15 +908,377 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupCacheDelete()
 				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
 			})
+			It("attachment cached before the uplink VLAN ledger uses cache reference as attachment ID", func() {
+				successfullyLoadConfig()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
+					cachedConf := *pluginConf
+					cachedConf.AttachmentID = ""
+					*args[1].(*localtypes.PluginConf) = cachedConf
+				}).Return(nil).Once()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				ipamMock.On("ExecDel", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				nsMock.On("GetNS", cmdArgs.Netns).Return(netNSMock, nil)
+				managerMock.On("ReleaseVF", pluginConf, cmdArgs.IfName, cmdArgs.ContainerID, netNSMock).Return(nil)
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil)
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil)
+				cleanupCacheDelete()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
 		})
 	})
 	Describe("CmdCheck", func() {
+		var (
+			prevResult map[string]interface{}
+		)
+
+		BeforeEach(func() {
+			prevResult = map[string]interface{}{
+				"cniVersion": "0.4.0",
+				"interfaces": []interface{}{
+					map[string]interface{}{"name": testValidContIFNames, "mac": testValidMAC, "sandbox": testValidNSPath},
+				},
+				"ips": []interface{}{
+					map[string]interface{}{"address": "192.168.100.101/24", "interface": 0},
+				},
+			}
+		})
+
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				netConf := pluginConf.NetConf
+				netConf.RawPrevResult = prevResult
+				*args[1].(*localtypes.NetConf) = netConf
+			}).Return(nil).Once()
+		}
+
+		successfullyLoadCache := func() {
+			successfullyLoadConfig()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(testValidCacheRef).Once()
+			cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.PluginConf) = *pluginConf
+			}).Return(nil).Once()
+		}
+
+		successfullyCheckRepresentor := func() {
+			successfullyLoadCache()
+			ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+			managerMock.On("CheckRepresentor", pluginConf).Return(nil).Once()
+		}
+
+		successfullyGetNS := func() {
+			successfullyCheckRepresentor()
+			nsMock.On("GetNS", cmdArgs.Netns).Return(netNSMock, nil).Once()
+			netNSMock.On("Close").Return(nil).Once()
+		}
+
+		matchContIface := mock.MatchedBy(func(iface *current.Interface) bool {
+			return iface.Name == testValidContIFNames && iface.Mac == testValidMAC
+		})
+		matchIPs := mock.MatchedBy(func(ips []*current.IPConfig) bool {
+			return len(ips) == 1 && ips[0].Address.String() == "192.168.100.101/24"
+		})
+
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("No prevResult", func() {
+				prevResult = nil
+				successfullyLoadConfig()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to load cache", func() {
+				successfullyLoadConfig()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				cacheMock.On("Load", testValidCacheRef, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed IPAM check", func() {
+				successfullyLoadCache()
+				ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("Representor drift", func() {
+				successfullyLoadCache()
+				ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				managerMock.On("CheckRepresentor", pluginConf).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(MatchError(errTest))
+			})
+			It("Container interface not in prevResult", func() {
+				prevResult["interfaces"] = []interface{}{map[string]interface{}{"name": "foo"}}
+				successfullyCheckRepresentor()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("VF drift", func() {
+				successfullyGetNS()
+				managerMock.On("CheckVF", pluginConf, matchContIface, matchIPs, netNSMock).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(MatchError(errTest))
+			})
+		})
 		Context("Successful scenarios", func() {
-			It("should not fail", func() {
+			It("success", func() {
+				successfullyGetNS()
+				managerMock.On("CheckVF", pluginConf, matchContIface, matchIPs, netNSMock).Return(nil).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("userspace driver", func() {
+				pluginConf.IsUserspaceDriver = true
+				successfullyCheckRepresentor()
 				Expect(plugin.CmdCheck(cmdArgs)).NotTo(HaveOccurred())
 			})
 		})
 	})
+	Describe("CmdGC", func() {
+		var (
+			validRef cache.StateRef = "mynet-valid-net1"
+			staleRef cache.StateRef = "mynet-stale-net1"
+			otherRef cache.StateRef = "mynet-other-stale-net1"
+		)
+
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				netConf := pluginConf.NetConf
+				netConf.ValidAttachments = []types.GCAttachment{{ContainerID: "valid", IfName: "net1"}}
+				*args[1].(*localtypes.NetConf) = netConf
+			}).Return(nil).Once()
+			cacheMock.On("GetStateRef", pluginConf.Name, "valid", "net1").Return(validRef).Once()
+		}
+
+		successfullyListCache := func() {
+			successfullyLoadConfig()
+			cacheMock.On("List", pluginConf.Name).Return([]cache.StateRef{validRef, staleRef, otherRef}, nil).Once()
+			cacheMock.On("Load", staleRef, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.PluginConf) = *pluginConf
+			}).Return(nil).Once()
+			cacheMock.On("Load", otherRef, mock.Anything).Run(func(args mock.Arguments) {
+				conf := *pluginConf
+				conf.Name = "mynet-other"
+				*args[1].(*localtypes.PluginConf) = conf
+			}).Return(nil).Once()
+		}
+
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to list cache", func() {
+				successfullyLoadConfig()
+				cacheMock.On("List", pluginConf.Name).Return(nil, errTest).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to reset VF", func() {
+				successfullyListCache()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(errTest).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+		})
+		Context("Successful scenarios", func() {
+			It("releases only stale attachments of the network", func() {
+				successfullyListCache()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", staleRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+		})
+	})
+	Describe("CmdStatus", func() {
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.NetConf) = pluginConf.NetConf
+			}).Return(nil).Once()
+		}
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).To(HaveOccurred())
+			})
+			It("Node is not ready", func() {
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", []string{testValidBridge}).Return(errTest).Once()
+				err := plugin.CmdStatus(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				cniErr, ok := err.(*types.Error)
+				Expect(ok).To(BeTrue())
+				Expect(cniErr.Code).To(Equal(errPluginNotAvailable))
+			})
+		})
+		Context("Successful scenarios", func() {
+			It("success", func() {
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", []string{testValidBridge}).Return(nil).Once()
+				ipamMock.On("ExecStatus", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("bridge is created on ADD", func() {
+				pluginConf.CreateBridge = true
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", []string(nil)).Return(nil).Once()
+				ipamMock.On("ExecStatus", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).NotTo(HaveOccurred())
+			})
+		})
+	})
+	Describe("Reconcile", func() {
+		var (
+			staleRef cache.StateRef = "mynet-stale-net1"
+			liveRef  cache.StateRef = "mynet-live-net1"
+			lockRef  cache.StateRef = "vlan-uplink.lock"
+		)
+
+		successfullyListCache := func() {
+			cacheMock.On("List", "").Return([]cache.StateRef{lockRef, liveRef, staleRef}, nil).Once()
+			cacheMock.On("Load", liveRef, mock.Anything).Run(func(args mock.Arguments) {
+				conf := *pluginConf
+				conf.NetNSPath = "/proc/1/ns/net"
+				*args[1].(*localtypes.PluginConf) = conf
+			}).Return(nil).Once()
+			cacheMock.On("Load", staleRef, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.PluginConf) = *pluginConf
+			}).Return(nil).Once()
+			nsMock.On("GetNS", "/proc/1/ns/net").Return(netNSMock, nil).Once()
+			netNSMock.On("Close").Return(nil).Once()
+			nsMock.On("GetNS", testValidNSPath).Return(nil, ns.NSPathNotExistErr{}).Once()
+		}
+
+		Context("Failed scenarios", func() {
+			It("Failed to list cache", func() {
+				cacheMock.On("List", "").Return(nil, errTest).Once()
+				Expect(plugin.Reconcile()).To(HaveOccurred())
+			})
+			It("Failed to lock attachment, other attachments are reconciled", func() {
+				cacheMock.On("List", "").Return([]cache.StateRef{liveRef, staleRef}, nil).Once()
+				lockMock.ExpectedCalls = nil
+				lockMock.On("Lock").Return(errTest).Once()
+				lockMock.On("Lock").Return(nil).Once()
+				lockMock.On("Unlock").Return(nil).Once()
+				cacheMock.On("Load", staleRef, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = *pluginConf
+				}).Return(nil).Once()
+				nsMock.On("GetNS", testValidNSPath).Return(nil, ns.NSPathNotExistErr{}).Once()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVF", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", staleRef).Return(nil).Once()
+				Expect(plugin.Reconcile()).To(HaveOccurred())
+				cacheMock.AssertNotCalled(t, "Load", liveRef, mock.Anything)
+				lockMock.AssertExpectations(t)
+			})
+			It("Failed to restore VF, should keep cache", func() {
+				successfullyListCache()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVF", pluginConf).Return(errTest).Once()
+				Expect(plugin.Reconcile()).To(HaveOccurred())
+			})
+		})
+		Context("Successful scenarios", func() {
+			It("releases only attachments with missing netns", func() {
+				successfullyListCache()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVF", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", staleRef).Return(nil).Once()
+				Expect(plugin.Reconcile()).NotTo(HaveOccurred())
+				// each attachment is loaded under its lock, lock file is not an attachment
+				Expect(lockedRefs).To(Equal([]cache.StateRef{liveRef, staleRef}))
+				cacheMock.AssertNotCalled(t, "Load", lockRef, mock.Anything)
+			})
+			Context("attachments cached without netns path", func() {
+				legacyRef := cache.StateRef("mynet-legacy-net1")
+				JustBeforeEach(func() {
+					pluginConf.NetNSPath = ""
+					cacheMock.On("List", "").Return([]cache.StateRef{legacyRef}, nil).Once()
+					cacheMock.On("Load", legacyRef, mock.Anything).Run(func(args mock.Arguments) {
+						*args[1].(*localtypes.PluginConf) = *pluginConf
+					}).Return(nil).Once()
+				})
+				It("releases attachment which VF was returned to init netns", func() {
+					managerMock.On("IsVFInInitNetns", pluginConf).Return(true).Once()
+					managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+					managerMock.On("RestoreVF", pluginConf).Return(nil).Once()
+					managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+					managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+					cacheMock.On("Delete", legacyRef).Return(nil).Once()
+					Expect(plugin.Reconcile()).NotTo(HaveOccurred())
+				})
+				It("keeps attachment which VF is in container netns", func() {
+					managerMock.On("IsVFInInitNetns", pluginConf).Return(false).Once()
+					Expect(plugin.Reconcile()).NotTo(HaveOccurred())
+				})
+				It("keeps attachment of VF with userspace driver as unknown", func() {
+					pluginConf.IsUserspaceDriver = true
+					Expect(plugin.Reconcile()).NotTo(HaveOccurred())
+					managerMock.AssertNotCalled(t, "IsVFInInitNetns", mock.Anything)
+				})
+			})
+			It("userspace driver", func() {
+				pluginConf.IsUserspaceDriver = true
+				successfullyListCache()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				managerMock.On("RestoreVFDriver", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", staleRef).Return(nil).Once()
+				Expect(plugin.Reconcile()).NotTo(HaveOccurred())
+			})
+		})
+	})
+	Describe("VLAN ledger", func() {
+		It("Dumps the ledger", func() {
+			managerMock.On("GetUplinkVlanLedger").Return(localtypes.UplinkVlanLedger{
+				"bond0": {100: {"mynet-a1b2c3d4e5f6-net1"}}}, nil).Once()
+			out := &bytes.Buffer{}
+			Expect(plugin.DumpVlanLedger(out)).NotTo(HaveOccurred())
+			Expect(out.String()).To(MatchJSON(`{"bond0": {"100": ["mynet-a1b2c3d4e5f6-net1"]}}`))
+		})
+		It("Failed to load the ledger", func() {
+			managerMock.On("GetUplinkVlanLedger").Return(nil, errTest).Once()
+			Expect(plugin.DumpVlanLedger(&bytes.Buffer{})).To(HaveOccurred())
+		})
+		It("Repairs the ledger with cached attachments", func() {
+			lockMock.On("TryLock").Return(true, nil)
+			cacheMock.On("List", "").Return([]cache.StateRef{"mynet-live-net1"}, nil).Twice()
+			managerMock.On("RepairUplinkVlanLedger", mock.Anything).Run(func(args mock.Arguments) {
+				isAttachment := args.Get(0).(func(string) (bool, error))
+				Expect(isAttachment("mynet-live-net1")).To(BeTrue())
+				Expect(isAttachment("mynet-stale-net1")).To(BeFalse())
+			}).Return(nil).Once()
+			Expect(plugin.RepairVlanLedger()).NotTo(HaveOccurred())
+			Expect(lockedRefs).To(Equal([]cache.StateRef{"mynet-live-net1", "mynet-stale-net1"}))
+			lockMock.AssertNumberOfCalls(t, "Unlock", 2)
+		})
+		It("Attachment with held lock is not stale", func() {
+			lockMock.On("TryLock").Return(false, nil).Once()
+			managerMock.On("RepairUplinkVlanLedger", mock.Anything).Run(func(args mock.Arguments) {
+				isAttachment := args.Get(0).(func(string) (bool, error))
+				// ADD in progress, the attachment is not cached yet
+				Expect(isAttachment("mynet-adding-net1")).To(BeTrue())
+			}).Return(nil).Once()
+			Expect(plugin.RepairVlanLedger()).NotTo(HaveOccurred())
+			lockMock.AssertNotCalled(t, "Unlock")
+		})
+		It("Failed to list cache", func() {
+			lockMock.On("TryLock").Return(true, nil).Once()
+			cacheMock.On("List", "").Return(nil, errTest).Once()
+			managerMock.On("RepairUplinkVlanLedger", mock.Anything).Run(func(args mock.Arguments) {
+				isAttachment := args.Get(0).(func(string) (bool, error))
+				_, err := isAttachment("mynet-live-net1")
+				Expect(err).To(HaveOccurred())
+			}).Return(errTest).Once()
+			Expect(plugin.RepairVlanLedger()).To(HaveOccurred())
+		})
+	})
 })
 
 var _ = Describe("Plugin - test plugin initialization", func() {
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/rs/zerolog/log"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
+	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
+)
+
+// Reconcile walks over all cached attachments and releases attachments
+// which network namespace no longer exists, e.g. after node reboot or
+// forced pod removal
+func (p *Plugin) Reconcile() error {
+	refs, err := p.cache.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list cached attachments: %v", err)
+	}
+
+	var errs []error
+	for _, ref := range refs {
+		if !cache.IsStateRef(string(ref)) {
+			log.Debug().Msgf("skip %s, not a cached attachment", ref)
+			continue
+		}
+		if reconcileErr := p.reconcileAttachment(ref); reconcileErr != nil {
+			errs = append(errs, reconcileErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcileAttachment releases the cached attachment if it is stale, the attachment is locked
+// and loaded under the lock to not race with ADD or DEL of the same attachment
+func (p *Plugin) reconcileAttachment(ref cache.StateRef) error {
+	unlock, err := p.lockAttachment(ref)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pluginConf := &localtypes.PluginConf{}
+	if loadErr := p.loadAttachment(ref, pluginConf); loadErr != nil {
+		// the attachment may be released by DEL after the cache is listed
+		log.Debug().Msgf("skip %s, failed to load cached attachment: %v", ref, loadErr)
+		return nil
+	}
+	if !p.isStaleAttachment(ref, pluginConf) {
+		return nil
+	}
+	log.Info().Msgf("Releasing stale attachment %s", ref)
+	if err = p.releaseStaleAttachment(pluginConf); err != nil {
+		return fmt.Errorf("failed to release attachment %s: %v", ref, err)
+	}
+	return p.cache.Delete(ref)
+}
+
+// isStaleAttachment returns true if network namespace of the attachment no longer exists
+func (p *Plugin) isStaleAttachment(ref cache.StateRef, pluginConf *localtypes.PluginConf) bool {
+	if pluginConf.NetNSPath == "" {
+		return p.isStaleLegacyAttachment(ref, pluginConf)
+	}
+	netns, err := p.netNS.GetNS(pluginConf.NetNSPath)
+	if err != nil {
+		switch err.(type) {
+		case ns.NSPathNotExistErr, ns.NSPathNotNSErr:
+			return true
+		}
+		log.Warn().Msgf("failed to open netns %s: %v", pluginConf.NetNSPath, err)
+		return false
+	}
+	_ = netns.Close()
+	return false
+}
+
+// isStaleLegacyAttachment checks attachment cached by older plugin version without netns path,
+// network namespace no longer exists if the VF netdevice was returned to init netns.
+// Attachments of VFs without netdevice in container netns are reported as unknown
+func (p *Plugin) isStaleLegacyAttachment(ref cache.StateRef, pluginConf *localtypes.PluginConf) bool {
+	if pluginConf.IsUserspaceDriver || pluginConf.VdpaDriver == utils.VdpaDriverVhost {
+		log.Warn().Msgf("attachment %s is unknown: cached without netns path by older plugin version "+
+			"and device %s has no netdevice in container netns, release it with CNI DEL if the container no longer exists",
+			ref, pluginConf.DeviceID)
+		return false
+	}
+	return p.manager.IsVFInInitNetns(pluginConf)
+}
+
+// releaseStaleAttachment detaches representor, restores VF which was returned
+// to init netns and resets VF configuration
+func (p *Plugin) releaseStaleAttachment(pluginConf *localtypes.PluginConf) error {
+	if err := p.manager.DetachRepresentor(pluginConf); err != nil {
+		log.Warn().Msgf("failed to detach representor: %v", err)
+	}
+	return p.restoreStaleVF(pluginConf)
+}
+
+// restoreStaleVF restores VF which was returned to init netns and resets VF configuration
+func (p *Plugin) restoreStaleVF(pluginConf *localtypes.PluginConf) error {
+	if !pluginConf.IsUserspaceDriver {
+		if err := p.manager.RestoreVF(pluginConf); err != nil {
+			return err
+		}
+	}
+	if err := p.manager.ResetVFConfig(pluginConf); err != nil {
+		return fmt.Errorf("error reseting VF: %q", err)
+	}
+	return p.manager.RestoreVFDriver(pluginConf)
+}
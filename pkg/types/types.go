@@ -10,11 +10,49 @@ type VfState struct {
 	AdminMAC     string `json:"admin_mac"`
 	EffectiveMAC string `json:"effective_mac"`
 	MTU          int    `json:"mtu"`
+	SpoofChk     bool   `json:"spoofchk"`
+	Trust        bool   `json:"trust"`
+	LinkState    uint32 `json:"link_state"`
+	MinTxRate    int    `json:"min_tx_rate"`
+	MaxTxRate    int    `json:"max_tx_rate"`
+	// driver of the VF before it was bound to the driver from bindDriver option
+	Driver string `json:"driver,omitempty"`
 }
 
 // RepState represents the state of the Representor
 type RepState struct {
 	MTU int `json:"mtu"`
+	// original values of the bridge port flags changed by the plugin, key is a flag name in sysfs
+	PortFlags map[string]bool `json:"port_flags,omitempty"`
+	// clsact qdisc for bandwidth limits was added by the plugin and should be removed on DEL
+	ClsactAdded bool `json:"clsact_added,omitempty"`
+}
+
+// UplinkVlanLedger records uplink VLANs added by the plugin,
+// maps uplink name to VLAN ID to the list of attachments which use the VLAN.
+// VNI ledger uses the same format and maps VXLAN device name to VNI of VNI filter entry
+type UplinkVlanLedger map[string]map[int][]string
+
+// PortFlags represents bridge port flags for the representor, flags which are not set are not changed
+type PortFlags struct {
+	Learning       *bool `json:"learning,omitempty"`
+	UnicastFlood   *bool `json:"unicastFlood,omitempty"`
+	MulticastFlood *bool `json:"multicastFlood,omitempty"`
+	BroadcastFlood *bool `json:"broadcastFlood,omitempty"`
+	Hairpin        *bool `json:"hairpin,omitempty"`
+	Isolated       *bool `json:"isolated,omitempty"`
+	NeighSuppress  *bool `json:"neighSuppress,omitempty"`
+	BpduGuard      *bool `json:"bpduGuard,omitempty"`
+	RootBlock      *bool `json:"rootBlock,omitempty"`
+}
+
+// BandwidthEntry represents bandwidth capability of the runtime configuration,
+// rates are in bits per second and bursts are in bits
+type BandwidthEntry struct {
+	IngressRate  uint64 `json:"ingressRate"`
+	IngressBurst uint64 `json:"ingressBurst"`
+	EgressRate   uint64 `json:"egressRate"`
+	EgressBurst  uint64 `json:"egressBurst"`
 }
 
 // Trunk represents configuration options for VLAN trunk
@@ -24,6 +62,12 @@ type Trunk struct {
 	ID    *int `json:"id,omitempty"`
 }
 
+// VniMapEntry represents mapping of a VLAN to VXLAN network identifier
+type VniMapEntry struct {
+	Vlan int    `json:"vlan"`
+	Vni  uint32 `json:"vni"`
+}
+
 // NetConf extends types.NetConf for accelerated-bridge-cni
 // defines accelerated-bridge-cni public API
 type NetConf struct {
@@ -33,21 +77,76 @@ type NetConf struct {
 	// bridge used to attach representor to it, default is "cni0"
 	// can contain comma separated list, e.g. bridge1,bridge2
 	Bridge string `json:"bridge,omitempty"`
+	// create the bridge with vlan_filtering enabled if it doesn't exist
+	CreateBridge bool `json:"createBridge,omitempty"`
+	// VLAN protocol for the created bridge, 802.1Q or 802.1ad, default is 802.1Q
+	BridgeVlanProtocol string `json:"bridgeVlanProtocol,omitempty"`
+	// MTU for the created bridge
+	BridgeMTU int `json:"bridgeMTU,omitempty"`
+	// attach PF or its bond to the created bridge
+	BridgeAttachUplink bool `json:"bridgeAttachUplink,omitempty"`
+	// VLAN protocol of the bridge, 802.1Q or 802.1ad; in 802.1ad mode vlan is a service VLAN
+	// and trunk is a list of customer VLANs carried inside the service VLAN
+	VlanProtocol string `json:"vlanProtocol,omitempty"`
 	// VLAN ID for VF
 	Vlan int `json:"vlan,omitempty"`
 	// VLAN Trunk configuration
 	Trunk []Trunk `json:"trunk"`
 	// enable setting matching vlan tags on the bridge uplink interface, default is false
 	SetUplinkVlan bool `json:"setUplinkVlan"`
+	// VXLAN device in external mode without VNI filtering used as bridge uplink instead of PF
+	VxlanDevice string `json:"vxlanDevice,omitempty"`
+	// VLAN to VNI mappings for the VXLAN device, should contain all VLANs from vlan and trunk options
+	VniMap []VniMapEntry `json:"vniMap,omitempty"`
 	// MAC as top level config option; required for CNIs that don't support runtimeConfig
 	MAC string `json:"mac,omitempty"`
+	// generate stable MAC for the VF if MAC is not configured
+	MACGenerate bool `json:"macGenerate,omitempty"`
+	// 3 bytes prefix of generated MACs, default is 02:00:00
+	MACPrefix string `json:"macPrefix,omitempty"`
+	// action on MAC conflict with the bridge FDB or other attachments, "warn", "fail" or "ignore"
+	MACConflictPolicy string `json:"macConflictPolicy,omitempty"`
+	// list of userspace drivers, VF bound to one of these drivers is handled without netdevice configuration
+	UserspaceDrivers []string `json:"userspaceDrivers,omitempty"`
+	// driver to bind the VF to during ADD, the original driver is restored during DEL; only vfio-pci is supported
+	BindDriver string `json:"bindDriver,omitempty"`
+	// way to set administrative MAC of the VF, "auto", "devlink" or "legacy"
+	MACStrategy string `json:"macStrategy,omitempty"`
 	// MTU for VF and representor
 	MTU int `json:"mtu"`
+	// VF spoof checking, "on" or "off"
+	SpoofChk string `json:"spoofchk,omitempty"`
+	// VF trust mode, "on" or "off"
+	Trust string `json:"trust,omitempty"`
+	// VF link state, "auto", "enable" or "disable"
+	LinkState string `json:"link_state,omitempty"`
+	// min and max TX rate for VF in Mbps, 0 disables the limit
+	MinTxRate *int `json:"min_tx_rate,omitempty"`
+	MaxTxRate *int `json:"max_tx_rate,omitempty"`
+	// bridge port flags for representor
+	PortFlags *PortFlags `json:"portFlags,omitempty"`
+	// disable learning on representor port and add static FDB entries for the VF MAC
+	StaticFdb bool `json:"staticFdb,omitempty"`
+	// number of gratuitous ARPs and unsolicited NAs sent for each IPAM address after IP configuration, 0 disables
+	AnnounceCount int `json:"announceCount,omitempty"`
+	// interval between announcements in milliseconds, default is 100
+	AnnounceInterval int `json:"announceInterval,omitempty"`
+	// wait until IPv6 addresses from IPAM complete DAD before the announcements
+	WaitForDAD bool `json:"waitForDAD,omitempty"`
+	// DAD timeout in seconds, default is 10
+	DADTimeout int `json:"dadTimeout,omitempty"`
+	// wait until the VF has carrier and the VF and the representor are operationally up
+	WaitForCarrier bool `json:"waitForCarrier,omitempty"`
+	// carrier timeout in seconds, default is 10
+	CarrierTimeout int `json:"carrierTimeout,omitempty"`
+	// per-interface sysctls of the pod interface, e.g. {"net.ipv6.conf.IFNAME.accept_ra": "0"}
+	Sysctl map[string]string `json:"sysctl,omitempty"`
 	// PCI address of a VF in valid sysfs format
 	DeviceID      string `json:"deviceID"`
 	RuntimeConfig struct {
-		Mac               string `json:"mac,omitempty"`
-		CNIDeviceInfoFile string `json:"CNIDeviceInfoFile,omitempty"`
+		Mac               string          `json:"mac,omitempty"`
+		CNIDeviceInfoFile string          `json:"CNIDeviceInfoFile,omitempty"`
+		Bandwidth         *BandwidthEntry `json:"bandwidth,omitempty"`
 	} `json:"runtimeConfig,omitempty"`
 }
 
@@ -56,6 +155,14 @@ type PluginConf struct {
 	NetConf
 	// IsUserspaceDriver indicate that VF using userspace driver
 	IsUserspaceDriver bool
+	// UserspaceDriver is a name of the userspace driver of the VF
+	UserspaceDriver string `json:"userspace_driver"`
+	// UserspaceDevice is a path of vfio group or uio device of the VF with userspace driver
+	UserspaceDevice string `json:"userspace_device"`
+	// UserspaceMAC is MAC of the VF with userspace driver which is seen by the userspace application
+	UserspaceMAC string `json:"userspace_mac"`
+	// UserspaceMTU is MTU of the representor of the VF with userspace driver, reported as MTU of the VF
+	UserspaceMTU int `json:"userspace_mtu,omitempty"`
 	// Stores the original VF state as it was prior to any operations done during cmdAdd flow
 	OrigVfState VfState `json:"orig_vf_state"`
 	// Stores the original Representor state as it was prior to any operations done during cmdAdd flow
@@ -72,8 +179,28 @@ type PluginConf struct {
 	Representor string `json:"representor"`
 	// VF index
 	VFID int `json:"vfid"`
+	// IsSF indicates that DeviceID is an auxiliary device of Scalable Function (SF) instead of a VF
+	IsSF bool `json:"is_sf"`
+	// SF number, used to find SF representor
+	SFNum int `json:"sfnum"`
+	// vDPA device created on top of the VF, e.g. vdpa0
+	VdpaDevice string `json:"vdpa_device"`
+	// vDPA bus driver of the vDPA device, vhost_vdpa or virtio_vdpa
+	VdpaDriver string `json:"vdpa_driver"`
+	// path to vhost-vdpa character device, e.g. /dev/vhost-vdpa-0
+	VdpaPath string `json:"vdpa_path"`
+	// MAC of the static FDB entries for representor; used during deletion
+	FdbMAC string `json:"fdb_mac"`
+	// ID of the attachment, used as owner of uplink VLANs in the uplink VLAN ledger
+	AttachmentID string `json:"attachment_id"`
+	// VXLAN device has VNI filtering enabled, VNI filter entries of vniMap are recorded in the VNI ledger
+	VniFilter bool `json:"vni_filter,omitempty"`
 	// VF names after in the container; used during deletion
 	ContIFNames string `json:"cont_if_names"`
+	// Path to the container network namespace; used to detect stale cache entries
+	NetNSPath string `json:"netns_path"`
 	// Internal presentation of VLAN Trunk config
 	Trunk []int `json:"trunk"`
+	// Customer VLANs (C-tags) from VLAN Trunk config which are allowed inside the service VLAN in 802.1ad mode
+	CTags []int `json:"ctags,omitempty"`
 }
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	arpPacketLen  = 28
+	arpHwEthernet = 1
+	arpOpRequest  = 1
+
+	ipv6HeaderLen      = 40
+	ipv6NextHdrICMPv6  = 58
+	ipv6NDHopLimit     = 255
+	icmpv6NALen        = 24
+	icmpv6TypeNA       = 136
+	icmpv6NAOverride   = 0x20000000
+	ndOptTargetLLAddr  = 2
+	ndOptTargetLLAddrL = 8
+
+	// upper-layer length and next header fields of IPv6 pseudo-header
+	ipv6PseudoHdrTail = 8
+	checksumWordLen   = 2
+	checksumFoldShift = 16
+	checksumFoldMask  = 0xffff
+)
+
+var (
+	broadcastMAC   = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	allNodesMAC    = net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x01}
+	allNodesIPv6   = net.ParseIP("ff02::1")
+	ipv6VersionTag = byte(0x60)
+)
+
+// Announcer sends unsolicited neighbor announcements for addresses of the link
+type Announcer interface {
+	SendGratuitousARP(netlink.Link, net.IP) error
+	SendUnsolicitedNA(netlink.Link, net.IP) error
+}
+
+type AnnouncerWrapper struct{}
+
+// SendGratuitousARP sends broadcast gratuitous ARP request for the IPv4 address from the link
+func (a *AnnouncerWrapper) SendGratuitousARP(link netlink.Link, ip net.IP) error {
+	packet, err := gratuitousARPPacket(link.Attrs().HardwareAddr, ip)
+	if err != nil {
+		return err
+	}
+	return sendPacket(link, unix.ETH_P_ARP, broadcastMAC, packet)
+}
+
+// SendUnsolicitedNA sends unsolicited neighbor advertisement for the IPv6 address
+// to all-nodes multicast address from the link
+func (a *AnnouncerWrapper) SendUnsolicitedNA(link netlink.Link, ip net.IP) error {
+	packet, err := unsolicitedNAPacket(link.Attrs().HardwareAddr, ip)
+	if err != nil {
+		return err
+	}
+	return sendPacket(link, unix.ETH_P_IPV6, allNodesMAC, packet)
+}
+
+// sendPacket sends the packet through the packet socket, ethernet header is added by the kernel
+func sendPacket(link netlink.Link, proto uint16, dst net.HardwareAddr, packet []byte) error {
+	// protocol is set only for the destination address, the socket is not used to receive packets
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open packet socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrLinklayer{
+		Protocol: htons(proto),
+		Ifindex:  link.Attrs().Index,
+		Halen:    uint8(len(dst)),
+	}
+	copy(addr.Addr[:], dst)
+	if err = unix.Sendto(fd, packet, 0, addr); err != nil {
+		return fmt.Errorf("failed to send packet from link %s: %v", link.Attrs().Name, err)
+	}
+	return nil
+}
+
+// gratuitousARPPacket returns ARP request with the IP as sender and target address
+func gratuitousARPPacket(mac net.HardwareAddr, ip net.IP) ([]byte, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("gratuitous ARP requires IPv4 address, got %s", ip)
+	}
+	if len(mac) != macLen {
+		return nil, fmt.Errorf("gratuitous ARP requires ethernet MAC, got %q", mac)
+	}
+	packet := make([]byte, arpPacketLen)
+	binary.BigEndian.PutUint16(packet[0:], arpHwEthernet)
+	binary.BigEndian.PutUint16(packet[2:], unix.ETH_P_IP)
+	packet[4] = macLen
+	packet[5] = net.IPv4len
+	binary.BigEndian.PutUint16(packet[6:], arpOpRequest)
+	copy(packet[8:], mac)
+	copy(packet[14:], ip4)
+	// target MAC is left zero
+	copy(packet[24:], ip4)
+	return packet, nil
+}
+
+// unsolicitedNAPacket returns IPv6 packet with neighbor advertisement for the IP,
+// override flag and target link-layer address option are set to update existing neighbor entries
+func unsolicitedNAPacket(mac net.HardwareAddr, ip net.IP) ([]byte, error) {
+	if ip.To4() != nil || ip.To16() == nil {
+		return nil, fmt.Errorf("neighbor advertisement requires IPv6 address, got %s", ip)
+	}
+	if len(mac) != macLen {
+		return nil, fmt.Errorf("neighbor advertisement requires ethernet MAC, got %q", mac)
+	}
+	icmp := make([]byte, icmpv6NALen+ndOptTargetLLAddrL)
+	icmp[0] = icmpv6TypeNA
+	binary.BigEndian.PutUint32(icmp[4:], icmpv6NAOverride)
+	copy(icmp[8:], ip.To16())
+	icmp[icmpv6NALen] = ndOptTargetLLAddr
+	icmp[icmpv6NALen+1] = 1 // option length in units of 8 bytes
+	copy(icmp[icmpv6NALen+2:], mac)
+	binary.BigEndian.PutUint16(icmp[2:], icmpv6Checksum(ip.To16(), allNodesIPv6, icmp))
+
+	packet := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(icmp))
+	packet[0] = ipv6VersionTag
+	binary.BigEndian.PutUint16(packet[4:], uint16(len(icmp)))
+	packet[6] = ipv6NextHdrICMPv6
+	packet[7] = ipv6NDHopLimit
+	copy(packet[8:], ip.To16())
+	copy(packet[24:], allNodesIPv6)
+	return append(packet, icmp...), nil
+}
+
+// icmpv6Checksum calculates ICMPv6 checksum including IPv6 pseudo-header
+func icmpv6Checksum(src, dst net.IP, icmp []byte) uint16 {
+	pseudo := make([]byte, 0, len(src)+len(dst)+len(icmp)+ipv6PseudoHdrTail)
+	pseudo = append(pseudo, src...)
+	pseudo = append(pseudo, dst...)
+	pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(icmp)))
+	pseudo = append(pseudo, 0, 0, 0, ipv6NextHdrICMPv6)
+	pseudo = append(pseudo, icmp...)
+	if len(pseudo)%checksumWordLen != 0 {
+		pseudo = append(pseudo, 0)
+	}
+	var sum uint32
+	for i := 0; i < len(pseudo); i += checksumWordLen {
+		sum += uint32(binary.BigEndian.Uint16(pseudo[i:]))
+	}
+	for sum>>checksumFoldShift != 0 {
+		sum = sum&checksumFoldMask + sum>>checksumFoldShift
+	}
+	return ^uint16(sum)
+}
+
+// htons converts value to network byte order
+func htons(v uint16) uint16 {
+	b := make([]byte, checksumWordLen)
+	binary.BigEndian.PutUint16(b, v)
+	return binary.NativeEndian.Uint16(b)
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "github.com/safchain/ethtool"
+
+// Ethtool represents limited subset of functions from ethtool package
+type Ethtool interface {
+	Features(string) (map[string]bool, error)
+}
+
+type EthtoolWrapper struct{}
+
+func (e *EthtoolWrapper) Features(ifName string) (map[string]bool, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return nil, err
+	}
+	defer et.Close()
+	return et.Features(ifName)
+}
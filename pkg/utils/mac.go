@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// MACConflictPolicyWarn logs MAC conflicts found in the bridge FDB or in cached attachments
+	MACConflictPolicyWarn = "warn"
+	// MACConflictPolicyFail fails ADD if MAC conflict is found
+	MACConflictPolicyFail = "fail"
+	// MACConflictPolicyIgnore disables MAC conflict detection
+	MACConflictPolicyIgnore = "ignore"
+	// DefaultMACPrefix is a default prefix of generated MACs, locally administered unicast
+	DefaultMACPrefix = "02:00:00"
+	// macPrefixLen is a length of MAC prefix in bytes
+	macPrefixLen = 3
+	macLen       = 6
+	// multicast and locally administered bits of the first MAC octet
+	macMulticastBit = 0x01
+	macLocalBit     = 0x02
+)
+
+// ValidateMAC checks that MAC can be assigned to the VF, multicast, broadcast and all-zero MACs are rejected
+func ValidateMAC(mac string) error {
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("MAC %q invalid: %v", mac, err)
+	}
+	if len(hwaddr) != macLen {
+		return fmt.Errorf("MAC %q invalid: value must be %d bytes ethernet MAC", mac, macLen)
+	}
+	switch {
+	case bytes.Equal(hwaddr, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}):
+		return fmt.Errorf("MAC %q invalid: broadcast MAC can't be assigned to the VF", mac)
+	case hwaddr[0]&macMulticastBit != 0:
+		return fmt.Errorf("MAC %q invalid: multicast MAC can't be assigned to the VF", mac)
+	case bytes.Equal(hwaddr, make(net.HardwareAddr, macLen)):
+		return fmt.Errorf("MAC %q invalid: all-zero MAC can't be assigned to the VF", mac)
+	}
+	return nil
+}
+
+// ParseMACPrefix parses 3 bytes MAC prefix, e.g. 02:00:00; the prefix must be locally administered unicast
+func ParseMACPrefix(prefix string) ([]byte, error) {
+	// reuse MAC parser by padding the prefix to the full MAC
+	hwaddr, err := net.ParseMAC(prefix + ":00:00:00")
+	if err != nil || len(hwaddr) != macLen {
+		return nil, fmt.Errorf("MAC prefix %q invalid: value must contain %d bytes, e.g. %s",
+			prefix, macPrefixLen, DefaultMACPrefix)
+	}
+	if hwaddr[0]&macMulticastBit != 0 || hwaddr[0]&macLocalBit == 0 {
+		return nil, fmt.Errorf("MAC prefix %q invalid: prefix must be locally administered unicast", prefix)
+	}
+	return hwaddr[:macPrefixLen], nil
+}
+
+// GenerateMAC returns MAC with the prefix, the rest of the MAC is derived from the hash of the provided values,
+// the same values always produce the same MAC
+func GenerateMAC(prefix string, values ...string) (string, error) {
+	prefixBytes, err := ParseMACPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(values, "/")))
+	hwaddr := make(net.HardwareAddr, 0, macLen)
+	hwaddr = append(hwaddr, prefixBytes...)
+	hwaddr = append(hwaddr, sum[:macLen-macPrefixLen]...)
+	return hwaddr.String(), nil
+}
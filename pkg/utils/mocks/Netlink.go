@@ -17,6 +17,29 @@ type Netlink struct {
 	mock.Mock
 }
 
+// AddrList provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) AddrList(_a0 netlink.Link, _a1 int) ([]netlink.Addr, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []netlink.Addr
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) []netlink.Addr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Addr)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BridgeVlanAdd provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
 func (_m *Netlink) BridgeVlanAdd(_a0 netlink.Link, _a1 uint16, _a2 bool, _a3 bool, _a4 bool, _a5 bool) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
@@ -68,6 +91,145 @@ func (_m *Netlink) BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error) {
 	return r0, r1
 }
 
+// BridgeVlanTunnelAdd provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) BridgeVlanTunnelAdd(_a0 netlink.Link, _a1 uint16, _a2 uint32) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint16, uint32) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BridgeVlanTunnelDel provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) BridgeVlanTunnelDel(_a0 netlink.Link, _a1 uint16, _a2 uint32) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint16, uint32) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DevLinkGetAllPortList provides a mock function with given fields:
+func (_m *Netlink) DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error) {
+	ret := _m.Called()
+
+	var r0 []*netlink.DevlinkPort
+	if rf, ok := ret.Get(0).(func() []*netlink.DevlinkPort); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*netlink.DevlinkPort)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevLinkGetDeviceByName provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) DevLinkGetDeviceByName(_a0 string, _a1 string) (*netlink.DevlinkDevice, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *netlink.DevlinkDevice
+	if rf, ok := ret.Get(0).(func(string, string) *netlink.DevlinkDevice); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkDevice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevlinkPortFnSet provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Netlink) DevlinkPortFnSet(_a0 string, _a1 string, _a2 uint32, _a3 netlink.DevlinkPortFnSetAttrs) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, netlink.DevlinkPortFnSetAttrs) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FilterDel provides a mock function with given fields: _a0
+func (_m *Netlink) FilterDel(_a0 netlink.Filter) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Filter) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FilterList provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) FilterList(_a0 netlink.Link, _a1 uint32) ([]netlink.Filter, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []netlink.Filter
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint32) []netlink.Filter); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Filter)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link, uint32) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkAdd provides a mock function with given fields: _a0
+func (_m *Netlink) LinkAdd(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkByIndex provides a mock function with given fields: index
 func (_m *Netlink) LinkByIndex(index int) (netlink.Link, error) {
 	ret := _m.Called(index)
@@ -262,3 +424,252 @@ func (_m *Netlink) LinkSetVfHardwareAddr(_a0 netlink.Link, _a1 int, _a2 net.Hard
 
 	return r0
 }
+
+// LinkSetVfRate provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Netlink) LinkSetVfRate(_a0 netlink.Link, _a1 int, _a2 int, _a3 int) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, int, int) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfSpoofchk provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfSpoofchk(_a0 netlink.Link, _a1 int, _a2 bool) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfState provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfState(_a0 netlink.Link, _a1 int, _a2 uint32) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, uint32) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfTrust provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfTrust(_a0 netlink.Link, _a1 int, _a2 bool) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSubscribeWithOptions provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSubscribeWithOptions(_a0 chan<- netlink.LinkUpdate, _a1 <-chan struct{}, _a2 netlink.LinkSubscribeOptions) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(chan<- netlink.LinkUpdate, <-chan struct{}, netlink.LinkSubscribeOptions) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkVxlanVniFilter provides a mock function with given fields: _a0
+func (_m *Netlink) LinkVxlanVniFilter(_a0 netlink.Link) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(netlink.Link) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MatchAllFilterAdd provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) MatchAllFilterAdd(_a0 *netlink.MatchAll, _a1 uint32) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.MatchAll, uint32) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighAdd provides a mock function with given fields: _a0
+func (_m *Netlink) NeighAdd(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighDel provides a mock function with given fields: _a0
+func (_m *Netlink) NeighDel(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighList provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) NeighList(_a0 int, _a1 int) ([]netlink.Neigh, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []netlink.Neigh
+	if rf, ok := ret.Get(0).(func(int, int) []netlink.Neigh); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Neigh)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QdiscAdd provides a mock function with given fields: _a0
+func (_m *Netlink) QdiscAdd(_a0 netlink.Qdisc) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Qdisc) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// QdiscDel provides a mock function with given fields: _a0
+func (_m *Netlink) QdiscDel(_a0 netlink.Qdisc) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Qdisc) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VlanFlowerFilterAdd provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) VlanFlowerFilterAdd(_a0 *netlink.Flower, _a1 uint16, _a2 uint32) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Flower, uint16, uint32) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VxlanVniAdd provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) VxlanVniAdd(_a0 netlink.Link, _a1 uint32) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint32) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VxlanVniDel provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) VxlanVniDel(_a0 netlink.Link, _a1 uint32) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint32) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VxlanVniList provides a mock function with given fields: _a0
+func (_m *Netlink) VxlanVniList(_a0 netlink.Link) ([]uint32, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []uint32
+	if rf, ok := ret.Get(0).(func(netlink.Link) []uint32); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint32)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -9,6 +9,71 @@ type SriovnetProvider struct {
 	mock.Mock
 }
 
+// GetNetDevicesFromAux provides a mock function with given fields: _a0
+func (_m *SriovnetProvider) GetNetDevicesFromAux(_a0 string) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSfIndexByAuxDev provides a mock function with given fields: _a0
+func (_m *SriovnetProvider) GetSfIndexByAuxDev(_a0 string) (int, error) {
+	ret := _m.Called(_a0)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSfRepresentor provides a mock function with given fields: _a0, _a1
+func (_m *SriovnetProvider) GetSfRepresentor(_a0 string, _a1 int) (string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUplinkRepresentor provides a mock function with given fields: _a0
 func (_m *SriovnetProvider) GetUplinkRepresentor(_a0 string) (string, error) {
 	ret := _m.Called(_a0)
@@ -30,6 +95,27 @@ func (_m *SriovnetProvider) GetUplinkRepresentor(_a0 string) (string, error) {
 	return r0, r1
 }
 
+// GetUplinkRepresentorFromAux provides a mock function with given fields: _a0
+func (_m *SriovnetProvider) GetUplinkRepresentorFromAux(_a0 string) (string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetVfRepresentor provides a mock function with given fields: _a0, _a1
 func (_m *SriovnetProvider) GetVfRepresentor(_a0 string, _a1 int) (string, error) {
 	ret := _m.Called(_a0, _a1)
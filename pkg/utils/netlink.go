@@ -6,6 +6,7 @@ import (
 
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -13,12 +14,94 @@ const (
 	linkTypeBond   = "bond"
 )
 
+const (
+	// TCClsFlagsSkipHW is a classifier flag which disables filter offload to HW
+	TCClsFlagsSkipHW uint32 = 1 << 0
+	// TCClsFlagsSkipSW is a classifier flag which disables filter in SW
+	TCClsFlagsSkipSW uint32 = 1 << 1
+)
+
+// bridge VLAN tunnel attributes, not defined in netlink package
+const (
+	iflaBridgeVlanTunnelInfo = nl.IFLA_BRIDGE_VLAN_INFO + 1
+	iflaBridgeVlanTunnelID   = 1
+	iflaBridgeVlanTunnelVid  = 2
+)
+
+// VXLAN VNI filter attributes and tunnel message header size, not defined in netlink package
+const (
+	vxlanVnifilterEntry      = 1
+	vxlanVnifilterEntryStart = 1
+	vxlanVnifilterEntryEnd   = 2
+	sizeofTunnelMsg          = 8
+	tunnelMsgIfindexOffset   = 4
+)
+
+// MaxVni is a max value of VXLAN network identifier
+const MaxVni = 1<<24 - 1
+
+const (
+	// VlanProtocol8021Q is a name of 802.1Q VLAN protocol
+	VlanProtocol8021Q = "802.1Q"
+	// VlanProtocol8021AD is a name of 802.1ad (QinQ) VLAN protocol
+	VlanProtocol8021AD = "802.1ad"
+)
+
+const (
+	// VfLinkStateAuto is a name of VF link state which follows PF link state
+	VfLinkStateAuto = "auto"
+	// VfLinkStateEnable is a name of VF link state which is always up
+	VfLinkStateEnable = "enable"
+	// VfLinkStateDisable is a name of VF link state which is always down
+	VfLinkStateDisable = "disable"
+)
+
+const (
+	// MACStrategyAuto sets VF MAC through devlink port function if it is supported, legacy way is used otherwise
+	MACStrategyAuto = "auto"
+	// MACStrategyDevlink sets VF MAC through devlink port function hw_addr
+	MACStrategyDevlink = "devlink"
+	// MACStrategyLegacy sets VF MAC through the PF with IFLA_VF_MAC
+	MACStrategyLegacy = "legacy"
+)
+
+// GetVfLinkState returns VF link state for the link state name
+func GetVfLinkState(name string) (uint32, error) {
+	switch name {
+	case VfLinkStateAuto:
+		return netlink.VF_LINK_STATE_AUTO, nil
+	case VfLinkStateEnable:
+		return netlink.VF_LINK_STATE_ENABLE, nil
+	case VfLinkStateDisable:
+		return netlink.VF_LINK_STATE_DISABLE, nil
+	}
+	return 0, fmt.Errorf("unknown VF link state %q, supported states: %s, %s, %s",
+		name, VfLinkStateAuto, VfLinkStateEnable, VfLinkStateDisable)
+}
+
+// GetVlanProtocolID returns ethertype for VLAN protocol name
+func GetVlanProtocolID(name string) (uint16, error) {
+	switch name {
+	case VlanProtocol8021Q:
+		return unix.ETH_P_8021Q, nil
+	case VlanProtocol8021AD:
+		return unix.ETH_P_8021AD, nil
+	}
+	return 0, fmt.Errorf("unknown VLAN protocol %q, supported protocols: %s, %s",
+		name, VlanProtocol8021Q, VlanProtocol8021AD)
+}
+
 // Netlink represents limited subset of functions from netlink package
 type Netlink interface {
 	LinkByName(string) (netlink.Link, error)
 	LinkByIndex(index int) (netlink.Link, error)
+	LinkAdd(netlink.Link) error
 	LinkSetVfHardwareAddr(netlink.Link, int, net.HardwareAddr) error
 	LinkSetHardwareAddr(netlink.Link, net.HardwareAddr) error
+	LinkSetVfSpoofchk(netlink.Link, int, bool) error
+	LinkSetVfTrust(netlink.Link, int, bool) error
+	LinkSetVfState(netlink.Link, int, uint32) error
+	LinkSetVfRate(netlink.Link, int, int, int) error
 	LinkSetUp(netlink.Link) error
 	LinkSetDown(netlink.Link) error
 	LinkSetNsFd(netlink.Link, int) error
@@ -30,6 +113,26 @@ type Netlink interface {
 	LinkSetMTU(netlink.Link, int) error
 	BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error)
 	LinkList() ([]netlink.Link, error)
+	AddrList(netlink.Link, int) ([]netlink.Addr, error)
+	QdiscAdd(netlink.Qdisc) error
+	QdiscDel(netlink.Qdisc) error
+	MatchAllFilterAdd(*netlink.MatchAll, uint32) error
+	VlanFlowerFilterAdd(*netlink.Flower, uint16, uint32) error
+	FilterDel(netlink.Filter) error
+	FilterList(netlink.Link, uint32) ([]netlink.Filter, error)
+	BridgeVlanTunnelAdd(netlink.Link, uint16, uint32) error
+	BridgeVlanTunnelDel(netlink.Link, uint16, uint32) error
+	LinkVxlanVniFilter(netlink.Link) (bool, error)
+	VxlanVniAdd(netlink.Link, uint32) error
+	VxlanVniDel(netlink.Link, uint32) error
+	VxlanVniList(netlink.Link) ([]uint32, error)
+	NeighAdd(*netlink.Neigh) error
+	NeighDel(*netlink.Neigh) error
+	NeighList(int, int) ([]netlink.Neigh, error)
+	LinkSubscribeWithOptions(chan<- netlink.LinkUpdate, <-chan struct{}, netlink.LinkSubscribeOptions) error
+	DevLinkGetDeviceByName(string, string) (*netlink.DevlinkDevice, error)
+	DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error)
+	DevlinkPortFnSet(string, string, uint32, netlink.DevlinkPortFnSetAttrs) error
 }
 
 // NetlinkWrapper wrapper for netlink package
@@ -46,6 +149,11 @@ func (n *NetlinkWrapper) LinkByIndex(index int) (netlink.Link, error) {
 	return netlink.LinkByIndex(index)
 }
 
+// LinkAdd is a wrapper for netlink.LinkAdd
+func (n *NetlinkWrapper) LinkAdd(link netlink.Link) error {
+	return netlink.LinkAdd(link)
+}
+
 // LinkSetVfHardwareAddr is a wrapper for netlink.LinkSetVfHardwareAddr
 func (n *NetlinkWrapper) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
 	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
@@ -56,6 +164,26 @@ func (n *NetlinkWrapper) LinkSetHardwareAddr(link netlink.Link, hwaddr net.Hardw
 	return netlink.LinkSetHardwareAddr(link, hwaddr)
 }
 
+// LinkSetVfSpoofchk is a wrapper for netlink.LinkSetVfSpoofchk
+func (n *NetlinkWrapper) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	return netlink.LinkSetVfSpoofchk(link, vf, check)
+}
+
+// LinkSetVfTrust is a wrapper for netlink.LinkSetVfTrust
+func (n *NetlinkWrapper) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	return netlink.LinkSetVfTrust(link, vf, state)
+}
+
+// LinkSetVfState is a wrapper for netlink.LinkSetVfState
+func (n *NetlinkWrapper) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
+	return netlink.LinkSetVfState(link, vf, state)
+}
+
+// LinkSetVfRate is a wrapper for netlink.LinkSetVfRate
+func (n *NetlinkWrapper) LinkSetVfRate(link netlink.Link, vf, minRate, maxRate int) error {
+	return netlink.LinkSetVfRate(link, vf, minRate, maxRate)
+}
+
 // LinkSetMTU is a wrapper for netlink.LinkSetMTU
 func (n *NetlinkWrapper) LinkSetMTU(link netlink.Link, mtu int) error {
 	return netlink.LinkSetMTU(link, mtu)
@@ -111,6 +239,306 @@ func (n *NetlinkWrapper) LinkList() ([]netlink.Link, error) {
 	return netlink.LinkList()
 }
 
+// AddrList is a wrapper for netlink.AddrList
+func (n *NetlinkWrapper) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+// QdiscAdd is a wrapper for netlink.QdiscAdd
+func (n *NetlinkWrapper) QdiscAdd(qdisc netlink.Qdisc) error {
+	return netlink.QdiscAdd(qdisc)
+}
+
+// QdiscDel is a wrapper for netlink.QdiscDel
+func (n *NetlinkWrapper) QdiscDel(qdisc netlink.Qdisc) error {
+	return netlink.QdiscDel(qdisc)
+}
+
+// FilterDel is a wrapper for netlink.FilterDel
+func (n *NetlinkWrapper) FilterDel(filter netlink.Filter) error {
+	return netlink.FilterDel(filter)
+}
+
+// NeighAdd is a wrapper for netlink.NeighAdd
+func (n *NetlinkWrapper) NeighAdd(neigh *netlink.Neigh) error {
+	return netlink.NeighAdd(neigh)
+}
+
+// NeighDel is a wrapper for netlink.NeighDel
+func (n *NetlinkWrapper) NeighDel(neigh *netlink.Neigh) error {
+	return netlink.NeighDel(neigh)
+}
+
+// NeighList is a wrapper for netlink.NeighList
+func (n *NetlinkWrapper) NeighList(linkIndex, family int) ([]netlink.Neigh, error) {
+	return netlink.NeighList(linkIndex, family)
+}
+
+// LinkSubscribeWithOptions is a wrapper for netlink.LinkSubscribeWithOptions
+func (n *NetlinkWrapper) LinkSubscribeWithOptions(ch chan<- netlink.LinkUpdate, done <-chan struct{},
+	options netlink.LinkSubscribeOptions) error {
+	return netlink.LinkSubscribeWithOptions(ch, done, options)
+}
+
+// FilterList is a wrapper for netlink.FilterList
+func (n *NetlinkWrapper) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return netlink.FilterList(link, parent)
+}
+
+// MatchAllFilterAdd adds matchall filter with classifier flags (e.g. skip_sw),
+// netlink.FilterAdd doesn't support flags for matchall filters
+func (n *NetlinkWrapper) MatchAllFilterAdd(filter *netlink.MatchAll, flags uint32) error {
+	req := newFilterAddRequest(filter)
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	actionsAttr := options.AddRtAttr(nl.TCA_MATCHALL_ACT, nil)
+	if err := netlink.EncodeActions(actionsAttr, filter.Actions); err != nil {
+		return err
+	}
+	if filter.ClassId != 0 {
+		options.AddRtAttr(nl.TCA_MATCHALL_CLASSID, nl.Uint32Attr(filter.ClassId))
+	}
+	if flags != 0 {
+		options.AddRtAttr(nl.TCA_MATCHALL_FLAGS, nl.Uint32Attr(flags))
+	}
+	req.AddData(options)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// VlanFlowerFilterAdd adds flower filter which matches VLAN ID of the outer VLAN tag with classifier flags
+// (e.g. skip_sw), protocol of the filter is a VLAN protocol of the tag, e.g. 802.1Q. Only actions of the filter
+// are used, other flower keys are not supported. netlink.FilterAdd doesn't support VLAN keys
+func (n *NetlinkWrapper) VlanFlowerFilterAdd(filter *netlink.Flower, vlanID uint16, flags uint32) error {
+	req := newFilterAddRequest(filter)
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	// VLAN keys are parsed by the kernel only if ethernet type key is a VLAN protocol
+	options.AddRtAttr(nl.TCA_FLOWER_KEY_ETH_TYPE, nl.Uint16Attr(htons(filter.Protocol)))
+	options.AddRtAttr(nl.TCA_FLOWER_KEY_VLAN_ID, nl.Uint16Attr(vlanID))
+	if flags != 0 {
+		options.AddRtAttr(nl.TCA_FLOWER_FLAGS, nl.Uint32Attr(flags))
+	}
+	actionsAttr := options.AddRtAttr(nl.TCA_FLOWER_ACT, nil)
+	if err := netlink.EncodeActions(actionsAttr, filter.Actions); err != nil {
+		return err
+	}
+	req.AddData(options)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// newFilterAddRequest returns request which creates the filter, filter options should be added by the caller
+func newFilterAddRequest(filter netlink.Filter) *nl.NetlinkRequest {
+	req := nl.NewNetlinkRequest(unix.RTM_NEWTFILTER, unix.NLM_F_CREATE|unix.NLM_F_EXCL|unix.NLM_F_ACK)
+	base := filter.Attrs()
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(base.LinkIndex),
+		Handle:  base.Handle,
+		Parent:  base.Parent,
+		Info:    netlink.MakeHandle(base.Priority, nl.Swap16(base.Protocol)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(filter.Type())))
+	return req
+}
+
+// DevLinkGetDeviceByName is a wrapper for netlink.DevLinkGetDeviceByName
+func (n *NetlinkWrapper) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return netlink.DevLinkGetDeviceByName(bus, device)
+}
+
+// DevLinkGetAllPortList is a wrapper for netlink.DevLinkGetAllPortList
+func (n *NetlinkWrapper) DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error) {
+	return netlink.DevLinkGetAllPortList()
+}
+
+// DevlinkPortFnSet is a wrapper for netlink.DevlinkPortFnSet
+func (n *NetlinkWrapper) DevlinkPortFnSet(bus, device string, portIndex uint32,
+	fnAttrs netlink.DevlinkPortFnSetAttrs) error {
+	return netlink.DevlinkPortFnSet(bus, device, portIndex, fnAttrs)
+}
+
+// BridgeVlanTunnelAdd adds VLAN to tunnel ID mapping (tunnel_info) for the bridge port,
+// netlink package doesn't support tunnel_info
+func (n *NetlinkWrapper) BridgeVlanTunnelAdd(link netlink.Link, vid uint16, tunnelID uint32) error {
+	return bridgeVlanTunnelModify(unix.RTM_SETLINK, link, vid, tunnelID)
+}
+
+// BridgeVlanTunnelDel removes VLAN to tunnel ID mapping (tunnel_info) from the bridge port
+func (n *NetlinkWrapper) BridgeVlanTunnelDel(link netlink.Link, vid uint16, tunnelID uint32) error {
+	return bridgeVlanTunnelModify(unix.RTM_DELLINK, link, vid, tunnelID)
+}
+
+func bridgeVlanTunnelModify(cmd int, link netlink.Link, vid uint16, tunnelID uint32) error {
+	req := nl.NewNetlinkRequest(cmd, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_BRIDGE)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	afSpec := nl.NewRtAttr(unix.IFLA_AF_SPEC, nil)
+	tunnelInfo := afSpec.AddRtAttr(iflaBridgeVlanTunnelInfo, nil)
+	tunnelInfo.AddRtAttr(iflaBridgeVlanTunnelID, nl.Uint32Attr(tunnelID))
+	tunnelInfo.AddRtAttr(iflaBridgeVlanTunnelVid, nl.Uint16Attr(vid))
+	req.AddData(afSpec)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// LinkVxlanVniFilter returns true if VNI filtering (vnifilter) is enabled on the VXLAN device,
+// netlink package doesn't support IFLA_VXLAN_VNIFILTER
+func (n *NetlinkWrapper) LinkVxlanVniFilter(link netlink.Link) (bool, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK)
+	if err != nil {
+		return false, err
+	}
+	if len(msgs) != 1 {
+		return false, fmt.Errorf("unexpected number of link messages for %s: %d", link.Attrs().Name, len(msgs))
+	}
+	return parseVxlanVniFilter(msgs[0])
+}
+
+// parseVxlanVniFilter returns value of IFLA_VXLAN_VNIFILTER attribute from RTM_NEWLINK message
+func parseVxlanVniFilter(m []byte) (bool, error) {
+	attrs, err := nl.ParseRouteAttr(m[unix.SizeofIfInfomsg:])
+	if err != nil {
+		return false, err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type != unix.IFLA_LINKINFO {
+			continue
+		}
+		infos, infoErr := nl.ParseRouteAttr(attr.Value)
+		if infoErr != nil {
+			return false, infoErr
+		}
+		for _, info := range infos {
+			if info.Attr.Type != unix.IFLA_INFO_DATA {
+				continue
+			}
+			data, dataErr := nl.ParseRouteAttr(info.Value)
+			if dataErr != nil {
+				return false, dataErr
+			}
+			for _, d := range data {
+				if d.Attr.Type == unix.IFLA_VXLAN_VNIFILTER && len(d.Value) > 0 {
+					return d.Value[0] != 0, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// tunnelMsg is a header of RTM_NEWTUNNEL, RTM_DELTUNNEL and RTM_GETTUNNEL messages (struct tunnel_msg)
+type tunnelMsg struct {
+	family  uint8
+	ifindex uint32
+}
+
+func (msg *tunnelMsg) Len() int {
+	return sizeofTunnelMsg
+}
+
+func (msg *tunnelMsg) Serialize() []byte {
+	b := make([]byte, sizeofTunnelMsg)
+	b[0] = msg.family
+	nl.NativeEndian().PutUint32(b[tunnelMsgIfindexOffset:], msg.ifindex)
+	return b
+}
+
+// VxlanVniAdd adds VNI filter entry to the VXLAN device with VNI filtering enabled,
+// netlink package doesn't support VNI filter entries
+func (n *NetlinkWrapper) VxlanVniAdd(link netlink.Link, vni uint32) error {
+	return vxlanVniModify(unix.RTM_NEWTUNNEL, unix.NLM_F_CREATE|unix.NLM_F_EXCL, link, vni)
+}
+
+// VxlanVniDel removes VNI filter entry from the VXLAN device with VNI filtering enabled
+func (n *NetlinkWrapper) VxlanVniDel(link netlink.Link, vni uint32) error {
+	return vxlanVniModify(unix.RTM_DELTUNNEL, 0, link, vni)
+}
+
+func vxlanVniModify(cmd, flags int, link netlink.Link, vni uint32) error {
+	req := nl.NewNetlinkRequest(cmd, unix.NLM_F_ACK|flags)
+	req.AddData(&tunnelMsg{family: unix.AF_BRIDGE, ifindex: uint32(link.Attrs().Index)})
+
+	entry := nl.NewRtAttr(unix.NLA_F_NESTED|vxlanVnifilterEntry, nil)
+	entry.AddRtAttr(vxlanVnifilterEntryStart, nl.Uint32Attr(vni))
+	req.AddData(entry)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// VxlanVniList returns VNIs of VNI filter entries of the VXLAN device
+func (n *NetlinkWrapper) VxlanVniList(link netlink.Link) ([]uint32, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETTUNNEL, unix.NLM_F_DUMP)
+	req.AddData(&tunnelMsg{family: unix.AF_BRIDGE, ifindex: uint32(link.Attrs().Index)})
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWTUNNEL)
+	if err != nil {
+		return nil, err
+	}
+	var vnis []uint32
+	for _, m := range msgs {
+		msgVnis, parseErr := parseVxlanVniList(m, uint32(link.Attrs().Index))
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		vnis = append(vnis, msgVnis...)
+	}
+	return vnis, nil
+}
+
+// parseVxlanVniList returns VNIs of VNI filter entries from RTM_NEWTUNNEL message of the device,
+// VNI ranges are expanded
+func parseVxlanVniList(m []byte, ifindex uint32) ([]uint32, error) {
+	if len(m) < sizeofTunnelMsg {
+		return nil, fmt.Errorf("tunnel message is too short: %d", len(m))
+	}
+	if nl.NativeEndian().Uint32(m[tunnelMsgIfindexOffset:sizeofTunnelMsg]) != ifindex {
+		return nil, nil
+	}
+	attrs, err := nl.ParseRouteAttr(m[sizeofTunnelMsg:])
+	if err != nil {
+		return nil, err
+	}
+	var vnis []uint32
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != vxlanVnifilterEntry {
+			continue
+		}
+		entryAttrs, entryErr := nl.ParseRouteAttr(attr.Value)
+		if entryErr != nil {
+			return nil, entryErr
+		}
+		var start, end uint32
+		for _, a := range entryAttrs {
+			switch a.Attr.Type & nl.NLA_TYPE_MASK {
+			case vxlanVnifilterEntryStart:
+				start = nl.NativeEndian().Uint32(a.Value)
+			case vxlanVnifilterEntryEnd:
+				end = nl.NativeEndian().Uint32(a.Value)
+			}
+		}
+		if end < start {
+			end = start
+		}
+		for vni := start; vni <= end; vni++ {
+			vnis = append(vnis, vni)
+		}
+	}
+	return vnis, nil
+}
+
 // BridgePVIDVlanAdd configure port VLAN id for link
 func BridgePVIDVlanAdd(nlink Netlink, link netlink.Link, vlanID int) error {
 	// pvid, egress untagged
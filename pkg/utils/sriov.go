@@ -6,6 +6,10 @@ import "github.com/Mellanox/sriovnet"
 type SriovnetProvider interface {
 	GetVfRepresentor(string, int) (string, error)
 	GetUplinkRepresentor(string) (string, error)
+	GetSfRepresentor(string, int) (string, error)
+	GetUplinkRepresentorFromAux(string) (string, error)
+	GetSfIndexByAuxDev(string) (int, error)
+	GetNetDevicesFromAux(string) ([]string, error)
 }
 
 type SriovnetWrapper struct{}
@@ -17,3 +21,19 @@ func (s *SriovnetWrapper) GetVfRepresentor(master string, vfid int) (string, err
 func (s *SriovnetWrapper) GetUplinkRepresentor(vfPciAddress string) (string, error) {
 	return sriovnet.GetUplinkRepresentor(vfPciAddress)
 }
+
+func (s *SriovnetWrapper) GetSfRepresentor(uplink string, sfNum int) (string, error) {
+	return sriovnet.GetSfRepresentor(uplink, sfNum)
+}
+
+func (s *SriovnetWrapper) GetUplinkRepresentorFromAux(auxDev string) (string, error) {
+	return sriovnet.GetUplinkRepresentorFromAux(auxDev)
+}
+
+func (s *SriovnetWrapper) GetSfIndexByAuxDev(auxDev string) (int, error) {
+	return sriovnet.GetSfIndexByAuxDev(auxDev)
+}
+
+func (s *SriovnetWrapper) GetNetDevicesFromAux(auxDev string) ([]string, error) {
+	return sriovnet.GetNetDevicesFromAux(auxDev)
+}
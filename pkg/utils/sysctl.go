@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SysctlIfNamePlaceholder is replaced with the pod interface name in sysctl keys
+	SysctlIfNamePlaceholder = "IFNAME"
+)
+
+var (
+	// sysctlParamRe matches name of per-interface sysctl parameter, e.g. accept_ra
+	sysctlParamRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+	// interfaceSysctlPrefixes are prefixes of per-interface sysctl keys
+	interfaceSysctlPrefixes = []string{"net.ipv4.conf.", "net.ipv6.conf."}
+)
+
+// Sysctl represents per-interface sysctl operations in the current netns
+type Sysctl interface {
+	SetInterfaceSysctl(string, string, string) error
+}
+
+type SysctlWrapper struct{}
+
+// SetInterfaceSysctl is a wrapper for SetInterfaceSysctl
+func (s *SysctlWrapper) SetInterfaceSysctl(ifName, key, value string) error {
+	return SetInterfaceSysctl(ifName, key, value)
+}
+
+// ParseInterfaceSysctl splits per-interface sysctl key net.ipv4.conf.<if>.<param> or net.ipv6.conf.<if>.<param>
+// into the key prefix, the interface name and the parameter. Global all and default entries are rejected
+func ParseInterfaceSysctl(key string) (prefix, ifName, param string, err error) {
+	for _, p := range interfaceSysctlPrefixes {
+		if !strings.HasPrefix(key, p) {
+			continue
+		}
+		// interface name may contain dots, e.g. VLAN interface, the parameter name doesn't
+		rest := strings.TrimPrefix(key, p)
+		sep := strings.LastIndex(rest, ".")
+		if sep <= 0 {
+			break
+		}
+		ifName, param = rest[:sep], rest[sep+1:]
+		if strings.Contains(ifName, "/") || !sysctlParamRe.MatchString(param) {
+			break
+		}
+		if ifName == "all" || ifName == "default" {
+			return "", "", "", fmt.Errorf("sysctl %q invalid: only sysctls of the pod interface can be set", key)
+		}
+		return p, ifName, param, nil
+	}
+	return "", "", "", fmt.Errorf("sysctl %q invalid: key must be net.ipv4.conf.%s.<param> or net.ipv6.conf.%s.<param>",
+		key, SysctlIfNamePlaceholder, SysctlIfNamePlaceholder)
+}
+
+// SetInterfaceSysctl sets per-interface sysctl of the interface in the current netns,
+// interface in the key must be IFNAME placeholder or the interface name
+func SetInterfaceSysctl(ifName, key, value string) error {
+	prefix, keyIfName, param, err := ParseInterfaceSysctl(key)
+	if err != nil {
+		return err
+	}
+	if keyIfName != SysctlIfNamePlaceholder && keyIfName != ifName {
+		return fmt.Errorf("sysctl %q invalid: key doesn't belong to interface %s", key, ifName)
+	}
+	// net.ipv4.conf. -> ipv4/conf
+	dir := strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(prefix, "net."), "."), ".", "/")
+	sysctlFile := filepath.Join(ProcSysNet, dir, ifName, param)
+	// file is not created if the parameter doesn't exist
+	f, err := os.OpenFile(sysctlFile, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open sysctl %s of interface %s: %v", param, ifName, err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(value); err != nil {
+		return fmt.Errorf("failed to set sysctl %s of interface %s to %q: %v", param, ifName, value, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Sysfs represents driver binding operations of PCI devices through sysfs
+type Sysfs interface {
+	GetDriver(string) (string, error)
+	UnbindDriver(string) error
+	BindDriver(string, string) error
+}
+
+type SysfsWrapper struct{}
+
+// GetDriver returns name of the driver of the PCI device, empty string is returned if the device is not bound
+func (s *SysfsWrapper) GetDriver(pciAddr string) (string, error) {
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(SysBusPci, pciAddr, "driver"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read driver of device %s: %v", pciAddr, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// UnbindDriver unbinds the PCI device from its driver, nothing is done if the device is not bound
+func (s *SysfsWrapper) UnbindDriver(pciAddr string) error {
+	driver, err := s.GetDriver(pciAddr)
+	if err != nil || driver == "" {
+		return err
+	}
+	unbindFile := filepath.Join(SysBusPci, pciAddr, "driver", "unbind")
+	//nolint:gosec
+	if err = os.WriteFile(unbindFile, []byte(pciAddr), 0200); err != nil {
+		return fmt.Errorf("failed to unbind device %s from driver %s: %v", pciAddr, driver, err)
+	}
+	return nil
+}
+
+// BindDriver binds the unbound PCI device to the driver,
+// driver_override is set during the bind to make sure that the device is probed by the requested driver only
+func (s *SysfsWrapper) BindDriver(pciAddr, driver string) error {
+	overrideFile := filepath.Join(SysBusPci, pciAddr, "driver_override")
+	//nolint:gosec
+	if err := os.WriteFile(overrideFile, []byte(driver), 0200); err != nil {
+		return fmt.Errorf("failed to set driver override %s for device %s: %v", driver, pciAddr, err)
+	}
+	defer func() {
+		// clear the override to let the device be bound to any driver later
+		//nolint:gosec
+		_ = os.WriteFile(overrideFile, []byte("\n"), 0200)
+	}()
+
+	bindFile := filepath.Join(filepath.Dir(SysBusPci), "drivers", driver, "bind")
+	//nolint:gosec
+	if err := os.WriteFile(bindFile, []byte(pciAddr), 0200); err != nil {
+		return fmt.Errorf("failed to bind device %s to driver %s: %v", pciAddr, driver, err)
+	}
+	return nil
+}
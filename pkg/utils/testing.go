@@ -30,6 +30,13 @@ var ts = tmpSysFs{
 	dirList: []string{
 		"sys/class/net",
 		"sys/bus/pci/devices",
+		"sys/bus/auxiliary/devices",
+		"sys/bus/vdpa/devices",
+		"sys/bus/vdpa/drivers/vhost_vdpa",
+		"sys/bus/vdpa/drivers/virtio_vdpa",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2/mlx5_core.vnet.0/vdpa0/vhost-vdpa-0",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.3/mlx5_core.vnet.1/vdpa1/virtio0/net/eth0",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.2/net/enp175s0f1s2",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/net/enp175s7",
@@ -37,12 +44,33 @@ var ts = tmpSysFs{
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1",
 		"sys/bus/pci/devices/0000:11:00.0",
 		"sys/bus/pci/devices/0000:12:00.0",
+		"sys/bus/pci/devices/0000:13:00.0/uio/uio0",
+		"sys/kernel/iommu_groups/42",
 		"sys/bus/pci/drivers/mlx5_core",
 		"sys/bus/pci/drivers/vfio-pci",
+		"sys/bus/pci/drivers/igb_uio",
+		"sys/class/net/pf0vf0/brport",
+		"sys/class/net/br-new/bridge",
+		"sys/class/net/br-qinq/bridge",
+		"sys/class/net/vxlan0/brport",
+		"sys/class/net/pf1vf0",
+		"proc/sys/net/ipv4/conf/net1",
+		"proc/sys/net/ipv6/conf/net1",
 	},
 	fileList: map[string][]byte{
-		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_numvfs": []byte("2"),
-		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/sriov_numvfs": []byte("0"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_numvfs":                  []byte("2"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/sriov_numvfs":                  []byte("0"),
+		"sys/class/net/pf0vf0/brport/learning":                                           []byte("1\n"),
+		"sys/class/net/pf0vf0/brport/hairpin_mode":                                       []byte("0\n"),
+		"sys/class/net/br-qinq/bridge/vlan_protocol":                                     []byte("0x88a8\n"),
+		"sys/class/net/vxlan0/brport/vlan_tunnel":                                        []byte("0\n"),
+		"sys/class/net/pf0vf0/phys_switch_id":                                            []byte("a8e4570003f65a08\n"),
+		"sys/class/net/pf1vf0/phys_switch_id":                                            []byte("b2e4570003f65a08\n"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1/phys_switch_id": []byte("a8e4570003f65a08\n"),
+
+		"proc/sys/net/ipv4/conf/net1/arp_notify": []byte("0"),
+		"proc/sys/net/ipv4/conf/net1/rp_filter":  []byte("2"),
+		"proc/sys/net/ipv6/conf/net1/accept_ra":  []byte("1"),
 	},
 	netSymlinks: map[string]string{
 		"sys/class/net/enp175s0f1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
@@ -62,6 +90,12 @@ var ts = tmpSysFs{
 		"sys/bus/pci/devices/0000:af:06.0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
 		"sys/bus/pci/devices/0000:af:06.1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1",
 		"sys/bus/pci/devices/0000:05:00.0": "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0",
+
+		"sys/bus/auxiliary/devices/mlx5_core.sf.2": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.2",
+
+		"sys/bus/pci/devices/0000:af:06.2": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2",
+		"sys/bus/vdpa/devices/vdpa0":       "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2/mlx5_core.vnet.0/vdpa0",
+		"sys/bus/vdpa/devices/vdpa1":       "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.3/mlx5_core.vnet.1/vdpa1",
 	},
 	vfSymlinks: map[string]string{
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
@@ -69,8 +103,16 @@ var ts = tmpSysFs{
 
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/physfn":  "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1",
-		"sys/bus/pci/devices/0000:11:00.0/driver":                  "sys/bus/pci/drivers/vfio-pci",
-		"sys/bus/pci/devices/0000:12:00.0/driver":                  "sys/bus/pci/drivers/mlx5_core",
+
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn2": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2/physfn":  "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1",
+
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.2/mlx5_core.vnet.0/vdpa0/driver": "sys/bus/vdpa/drivers/vhost_vdpa",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.3/mlx5_core.vnet.1/vdpa1/driver": "sys/bus/vdpa/drivers/virtio_vdpa",
+		"sys/bus/pci/devices/0000:11:00.0/driver":                                        "sys/bus/pci/drivers/vfio-pci",
+		"sys/bus/pci/devices/0000:12:00.0/driver":                                        "sys/bus/pci/drivers/mlx5_core",
+		"sys/bus/pci/devices/0000:13:00.0/driver":                                        "sys/bus/pci/drivers/igb_uio",
+		"sys/bus/pci/devices/0000:11:00.0/iommu_group":                                   "sys/kernel/iommu_groups/42",
 	},
 }
 
@@ -114,7 +156,10 @@ func CreateTmpSysFs() error {
 	}
 
 	SysBusPci = filepath.Join(ts.dirRoot, SysBusPci)
+	SysBusAux = filepath.Join(ts.dirRoot, SysBusAux)
+	VdpaBus = filepath.Join(ts.dirRoot, VdpaBus)
 	NetDirectory = filepath.Join(ts.dirRoot, NetDirectory)
+	ProcSysNet = filepath.Join(ts.dirRoot, ProcSysNet)
 	return nil
 }
 
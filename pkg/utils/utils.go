@@ -14,8 +14,25 @@ var (
 	NetDirectory = "/sys/class/net"
 	// SysBusPci is sysfs pci device directory
 	SysBusPci = "/sys/bus/pci/devices"
-	// UserspaceDrivers is a list of driver names that don't have netlink representation for their devices
-	UserspaceDrivers = []string{"vfio-pci"}
+	// SysBusAux is sysfs auxiliary device directory
+	SysBusAux = "/sys/bus/auxiliary/devices"
+	// ProcSysNet is procfs directory of network sysctls
+	ProcSysNet = "/proc/sys/net"
+	// UserspaceDrivers is a default list of driver names that don't have netlink representation for their devices
+	UserspaceDrivers = []string{DriverVfioPci, DriverIgbUio, DriverUioPciGeneric}
+	// vfioDevDir is a directory of vfio group devices
+	vfioDevDir = "/dev/vfio"
+	// uioDevDir is a directory of uio devices
+	uioDevDir = "/dev"
+)
+
+const (
+	// DriverVfioPci is a name of vfio-pci driver
+	DriverVfioPci = "vfio-pci"
+	// DriverIgbUio is a name of DPDK igb_uio driver
+	DriverIgbUio = "igb_uio"
+	// DriverUioPciGeneric is a name of uio_pci_generic driver
+	DriverUioPciGeneric = "uio_pci_generic"
 )
 
 // GetSriovNumVfs takes in a PF name(ifName) as string and returns number of VF configured as int
@@ -70,6 +87,15 @@ func GetVfid(addr, pfName string) (int, error) {
 	return id, fmt.Errorf("unable to get VF ID with PF: %s and VF pci address %v", pfName, addr)
 }
 
+// IsAuxDevice returns true if the device ID is a name of an auxiliary device, e.g. mlx5_core.sf.2
+func IsAuxDevice(deviceID string) bool {
+	if deviceID == "" {
+		return false
+	}
+	_, err := os.Lstat(filepath.Join(SysBusAux, deviceID))
+	return err == nil
+}
+
 // GetVFLinkName returns VF's network interface name given it's PCI addr
 func GetVFLinkName(pciAddr string) (string, error) {
 	vfDir := filepath.Join(SysBusPci, pciAddr, "net")
@@ -94,22 +120,129 @@ func GetVFLinkName(pciAddr string) (string, error) {
 	return names[0], nil
 }
 
-// HasUserspaceDriver checks if a device is attached to userspace driver
+// HasUserspaceDriver checks if a device is attached to userspace driver from the default UserspaceDrivers list
 func HasUserspaceDriver(pciAddr string) (bool, error) {
+	driver, err := GetUserspaceDriver(pciAddr, UserspaceDrivers)
+	return driver != "", err
+}
+
+// GetUserspaceDriver returns name of the device driver if it is one of the provided userspace drivers,
+// empty string is returned if the device is attached to another driver
+func GetUserspaceDriver(pciAddr string, drivers []string) (string, error) {
 	driverLink := filepath.Join(SysBusPci, pciAddr, "driver")
 	driverPath, err := filepath.EvalSymlinks(driverLink)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	driverStat, err := os.Stat(driverPath)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	driverName := driverStat.Name()
-	for _, drv := range UserspaceDrivers {
+	for _, drv := range drivers {
 		if driverName == drv {
-			return true, nil
+			return driverName, nil
 		}
 	}
-	return false, nil
+	return "", nil
+}
+
+// GetUserspaceDevicePath returns path of the device which is used by userspace to access the PCI device:
+// vfio group device for vfio-pci driver or uio device for uio drivers,
+// empty string is returned if the driver doesn't provide such device
+func GetUserspaceDevicePath(pciAddr, driver string) (string, error) {
+	if driver == DriverVfioPci {
+		groupPath, err := os.Readlink(filepath.Join(SysBusPci, pciAddr, "iommu_group"))
+		if err != nil {
+			return "", fmt.Errorf("failed to get IOMMU group of device %s: %v", pciAddr, err)
+		}
+		return filepath.Join(vfioDevDir, filepath.Base(groupPath)), nil
+	}
+
+	uioDir := filepath.Join(SysBusPci, pciAddr, "uio")
+	if _, err := os.Lstat(uioDir); err != nil {
+		return "", nil
+	}
+	fInfos, err := os.ReadDir(uioDir)
+	if err != nil || len(fInfos) == 0 {
+		return "", fmt.Errorf("failed to get uio device of device %s: %v", pciAddr, err)
+	}
+	return filepath.Join(uioDevDir, fInfos[0].Name()), nil
+}
+
+// GetBridgePortFlag returns value of the bridge port flag from sysfs, e.g. learning or hairpin_mode
+func GetBridgePortFlag(ifName, flag string) (bool, error) {
+	flagFile := filepath.Join(NetDirectory, ifName, "brport", flag)
+	data, err := os.ReadFile(flagFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read bridge port flag %s of device %q: %v", flag, ifName, err)
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse bridge port flag %s of device %q: %v", flag, ifName, err)
+	}
+	return value != 0, nil
+}
+
+// SetBridgePortFlag sets value of the bridge port flag in sysfs
+func SetBridgePortFlag(ifName, flag string, value bool) error {
+	flagFile := filepath.Join(NetDirectory, ifName, "brport", flag)
+	data := []byte("0")
+	if value {
+		data = []byte("1")
+	}
+	//nolint:gosec
+	if err := os.WriteFile(flagFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to set bridge port flag %s of device %q: %v", flag, ifName, err)
+	}
+	return nil
+}
+
+// GetBridgeVlanProtocol returns VLAN protocol of the bridge from sysfs
+func GetBridgeVlanProtocol(bridge string) (uint16, error) {
+	protoFile := filepath.Join(NetDirectory, bridge, "bridge", "vlan_protocol")
+	data, err := os.ReadFile(protoFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read VLAN protocol of bridge %q: %v", bridge, err)
+	}
+	proto, err := strconv.ParseUint(strings.TrimSpace(string(data)), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse VLAN protocol of bridge %q: %v", bridge, err)
+	}
+	return uint16(proto), nil
+}
+
+// SetBridgeVlanProtocol sets VLAN protocol of the bridge in sysfs, e.g. 0x8100 for 802.1Q
+func SetBridgeVlanProtocol(bridge string, proto uint16) error {
+	protoFile := filepath.Join(NetDirectory, bridge, "bridge", "vlan_protocol")
+	//nolint:gosec
+	if err := os.WriteFile(protoFile, []byte(fmt.Sprintf("%#04x", proto)), 0644); err != nil {
+		return fmt.Errorf("failed to set VLAN protocol of bridge %q: %v", bridge, err)
+	}
+	return nil
+}
+
+// GetPfPciAddress returns PCI address of the PF netdevice
+func GetPfPciAddress(pfName string) (string, error) {
+	devLink := filepath.Join(NetDirectory, pfName, "device")
+	devPath, err := os.Readlink(devLink)
+	if err != nil {
+		return "", fmt.Errorf("failed to get PCI address of device %q: %v", pfName, err)
+	}
+	return filepath.Base(devPath), nil
+}
+
+// GetPhysSwitchID returns switch ID of the netdevice from sysfs,
+// all ports of the same eswitch have the same switch ID
+func GetPhysSwitchID(ifName string) (string, error) {
+	switchIDFile := filepath.Join(NetDirectory, ifName, "phys_switch_id")
+	data, err := os.ReadFile(switchIDFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read switch ID of device %q: %v", ifName, err)
+	}
+	switchID := strings.TrimSpace(string(data))
+	if switchID == "" {
+		return "", fmt.Errorf("device %q has no switch ID", ifName)
+	}
+	return switchID, nil
 }
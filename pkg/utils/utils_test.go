@@ -3,8 +3,12 @@ package utils
 import (
 	"errors"
 	"net"
+	"os"
+	"path/filepath"
 
 	"github.com/vishvananda/netlink"
+	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -52,6 +56,34 @@ var _ = Describe("Utils", func() {
 			Expect(err).To(HaveOccurred(), "Not existing interface should return an error")
 		})
 	})
+	Context("Checking IsAuxDevice function", func() {
+		It("Auxiliary device", func() {
+			Expect(IsAuxDevice("mlx5_core.sf.2")).To(BeTrue())
+		})
+		It("PCI device", func() {
+			Expect(IsAuxDevice("0000:af:06.0")).To(BeFalse())
+		})
+		It("Empty device ID", func() {
+			Expect(IsAuxDevice("")).To(BeFalse())
+		})
+	})
+	Context("Checking GetVdpaDevice function", func() {
+		It("VF with vhost-vdpa device", func() {
+			dev, err := GetVdpaDevice("0000:af:06.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dev).To(Equal(&VdpaDevice{Name: "vdpa0", Driver: VdpaDriverVhost, Path: "/dev/vhost-vdpa-0"}))
+		})
+		It("VF with virtio-vdpa device", func() {
+			dev, err := GetVdpaDevice("0000:af:06.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dev).To(Equal(&VdpaDevice{Name: "vdpa1", Driver: VdpaDriverVirtio, NetDev: "eth0"}))
+		})
+		It("VF without vDPA device", func() {
+			dev, err := GetVdpaDevice("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dev).To(BeNil())
+		})
+	})
 	Context("Checking HasUserspaceDriver function", func() {
 		It("Use userspace driver", func() {
 			result, err := HasUserspaceDriver("0000:11:00.0")
@@ -65,6 +97,329 @@ var _ = Describe("Utils", func() {
 		})
 	})
 
+	Context("Checking GenerateMAC function", func() {
+		It("Same values produce the same MAC", func() {
+			mac1, err := GenerateMAC(DefaultMACPrefix, "mynet", "default/vm1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			mac2, err := GenerateMAC(DefaultMACPrefix, "mynet", "default/vm1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac1).To(Equal(mac2))
+			Expect(mac1).To(HavePrefix("02:00:00:"))
+			_, err = net.ParseMAC(mac1)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("Different values produce different MACs", func() {
+			mac1, err := GenerateMAC(DefaultMACPrefix, "mynet", "default/vm1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			mac2, err := GenerateMAC(DefaultMACPrefix, "mynet", "default/vm1", "net2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac1).NotTo(Equal(mac2))
+		})
+		It("Invalid prefix", func() {
+			_, err := GenerateMAC("02:00", "mynet")
+			Expect(err).To(HaveOccurred())
+		})
+		It("Multicast prefix", func() {
+			_, err := GenerateMAC("03:00:00", "mynet")
+			Expect(err).To(HaveOccurred())
+		})
+		It("Globally administered prefix", func() {
+			_, err := GenerateMAC("00:11:22", "mynet")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking ValidateMAC function", func() {
+		It("Valid unicast MAC", func() {
+			Expect(ValidateMAC("b2:ec:90:4c:5b:11")).NotTo(HaveOccurred())
+		})
+		It("Malformed MAC", func() {
+			Expect(ValidateMAC("b2:ec:90:4c:5b")).To(HaveOccurred())
+		})
+		It("Infiniband MAC", func() {
+			Expect(ValidateMAC("00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01")).To(HaveOccurred())
+		})
+		It("Multicast MAC", func() {
+			Expect(ValidateMAC("01:00:5e:00:00:01")).To(HaveOccurred())
+		})
+		It("Broadcast MAC", func() {
+			Expect(ValidateMAC("ff:ff:ff:ff:ff:ff")).To(HaveOccurred())
+		})
+		It("All-zero MAC", func() {
+			Expect(ValidateMAC("00:00:00:00:00:00")).To(HaveOccurred())
+		})
+	})
+	Context("Checking announcement packets", func() {
+		mac, _ := net.ParseMAC("b2:ec:90:4c:5b:11")
+		It("Gratuitous ARP", func() {
+			packet, err := gratuitousARPPacket(mac, net.ParseIP("192.168.1.10"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(packet).To(Equal([]byte{
+				0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x01,
+				0xb2, 0xec, 0x90, 0x4c, 0x5b, 0x11, 192, 168, 1, 10,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 192, 168, 1, 10,
+			}))
+		})
+		It("Gratuitous ARP for IPv6 address", func() {
+			_, err := gratuitousARPPacket(mac, net.ParseIP("fd00::10"))
+			Expect(err).To(HaveOccurred())
+		})
+		It("Unsolicited NA", func() {
+			ip := net.ParseIP("fd00::10")
+			packet, err := unsolicitedNAPacket(mac, ip)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(packet).To(HaveLen(72))
+			// IPv6 header
+			Expect(packet[0:8]).To(Equal([]byte{0x60, 0, 0, 0, 0, 32, 58, 255}))
+			Expect(net.IP(packet[8:24]).Equal(ip)).To(BeTrue())
+			Expect(net.IP(packet[24:40]).Equal(net.ParseIP("ff02::1"))).To(BeTrue())
+			// NA with override flag, target address and target link-layer address option
+			icmp := packet[40:]
+			Expect(icmp[0]).To(Equal(byte(136)))
+			Expect(icmp[4]).To(Equal(byte(0x20)))
+			Expect(net.IP(icmp[8:24]).Equal(ip)).To(BeTrue())
+			Expect(icmp[24:32]).To(Equal([]byte{2, 1, 0xb2, 0xec, 0x90, 0x4c, 0x5b, 0x11}))
+			// checksum of the message with valid checksum is zero
+			Expect(icmpv6Checksum(ip, net.ParseIP("ff02::1"), icmp)).To(BeZero())
+		})
+		It("Unsolicited NA for IPv4 address", func() {
+			_, err := unsolicitedNAPacket(mac, net.ParseIP("192.168.1.10"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking ParseInterfaceSysctl function", func() {
+		It("IPv4 sysctl with IFNAME placeholder", func() {
+			prefix, ifName, param, err := ParseInterfaceSysctl("net.ipv4.conf.IFNAME.arp_notify")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prefix).To(Equal("net.ipv4.conf."))
+			Expect(ifName).To(Equal("IFNAME"))
+			Expect(param).To(Equal("arp_notify"))
+		})
+		It("IPv6 sysctl of interface with dot in the name", func() {
+			_, ifName, param, err := ParseInterfaceSysctl("net.ipv6.conf.net1.100.accept_ra")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ifName).To(Equal("net1.100"))
+			Expect(param).To(Equal("accept_ra"))
+		})
+		It("Global sysctl", func() {
+			_, _, _, err := ParseInterfaceSysctl("net.ipv4.ip_forward")
+			Expect(err).To(HaveOccurred())
+		})
+		It("all and default entries", func() {
+			_, _, _, err := ParseInterfaceSysctl("net.ipv4.conf.all.rp_filter")
+			Expect(err).To(HaveOccurred())
+			_, _, _, err = ParseInterfaceSysctl("net.ipv6.conf.default.disable_ipv6")
+			Expect(err).To(HaveOccurred())
+		})
+		It("Path traversal", func() {
+			_, _, _, err := ParseInterfaceSysctl("net.ipv4.conf.net1/../../all.rp_filter")
+			Expect(err).To(HaveOccurred())
+			_, _, _, err = ParseInterfaceSysctl("net.ipv4.conf.net1.rp_filter/../x")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SetInterfaceSysctl function", func() {
+		It("Set sysctl of the interface", func() {
+			Expect(SetInterfaceSysctl("net1", "net.ipv4.conf.IFNAME.rp_filter", "1")).NotTo(HaveOccurred())
+			data, err := os.ReadFile(filepath.Join(ProcSysNet, "ipv4/conf/net1/rp_filter"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("1"))
+		})
+		It("Sysctl of other interface", func() {
+			Expect(SetInterfaceSysctl("net1", "net.ipv4.conf.eth0.rp_filter", "1")).To(HaveOccurred())
+		})
+		It("Not existing parameter", func() {
+			Expect(SetInterfaceSysctl("net1", "net.ipv4.conf.net1.not_existing", "1")).To(HaveOccurred())
+			_, err := os.Stat(filepath.Join(ProcSysNet, "ipv4/conf/net1/not_existing"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+	Context("Checking SysfsWrapper functions", func() {
+		var s *SysfsWrapper
+		BeforeEach(func() {
+			s = &SysfsWrapper{}
+		})
+		It("Get driver of the device", func() {
+			result, err := s.GetDriver("0000:12:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("mlx5_core"))
+		})
+		It("Get driver of unbound device", func() {
+			result, err := s.GetDriver("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeEmpty())
+		})
+		It("Unbind device", func() {
+			Expect(s.UnbindDriver("0000:12:00.0")).NotTo(HaveOccurred())
+			data, err := os.ReadFile(filepath.Join(SysBusPci, "..", "drivers", "mlx5_core", "unbind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("0000:12:00.0"))
+		})
+		It("Bind device", func() {
+			Expect(s.BindDriver("0000:12:00.0", DriverVfioPci)).NotTo(HaveOccurred())
+			data, err := os.ReadFile(filepath.Join(SysBusPci, "..", "drivers", DriverVfioPci, "bind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("0000:12:00.0"))
+			// driver override is cleared after bind
+			data, err = os.ReadFile(filepath.Join(SysBusPci, "0000:12:00.0", "driver_override"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("\n"))
+		})
+		It("Bind device to missing driver", func() {
+			Expect(s.BindDriver("0000:12:00.0", "missing")).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetUserspaceDriver function", func() {
+		It("Use DPDK userspace driver", func() {
+			result, err := GetUserspaceDriver("0000:13:00.0", UserspaceDrivers)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(DriverIgbUio))
+		})
+		It("Driver is not in the configured list", func() {
+			result, err := GetUserspaceDriver("0000:13:00.0", []string{DriverVfioPci})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeEmpty())
+		})
+	})
+	Context("Checking GetUserspaceDevicePath function", func() {
+		It("vfio-pci driver", func() {
+			result, err := GetUserspaceDevicePath("0000:11:00.0", DriverVfioPci)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("/dev/vfio/42"))
+		})
+		It("uio driver", func() {
+			result, err := GetUserspaceDevicePath("0000:13:00.0", DriverIgbUio)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("/dev/uio0"))
+		})
+		It("Driver without userspace device", func() {
+			result, err := GetUserspaceDevicePath("0000:12:00.0", "mlx5_core")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeEmpty())
+		})
+	})
+	Context("Checking GetBridgePortFlag function", func() {
+		It("Existing flag", func() {
+			result, err := GetBridgePortFlag("pf0vf0", "learning")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeTrue())
+		})
+		It("Not existing flag", func() {
+			_, err := GetBridgePortFlag("pf0vf0", "unknown_flag")
+			Expect(err).To(HaveOccurred())
+		})
+		It("Not a bridge port", func() {
+			_, err := GetBridgePortFlag("enp175s6", "learning")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SetBridgePortFlag function", func() {
+		It("Set and read flag", func() {
+			Expect(SetBridgePortFlag("pf0vf0", "hairpin_mode", true)).NotTo(HaveOccurred())
+			result, err := GetBridgePortFlag("pf0vf0", "hairpin_mode")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeTrue())
+			Expect(SetBridgePortFlag("pf0vf0", "hairpin_mode", false)).NotTo(HaveOccurred())
+		})
+		It("Not a bridge port", func() {
+			Expect(SetBridgePortFlag("enp175s6", "learning", false)).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetBridgeVlanProtocol function", func() {
+		It("Existing bridge", func() {
+			result, err := GetBridgeVlanProtocol("br-qinq")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(uint16(0x88a8)))
+		})
+		It("Not a bridge", func() {
+			_, err := GetBridgeVlanProtocol("enp175s6")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetPfPciAddress function", func() {
+		It("Existing PF", func() {
+			result, err := GetPfPciAddress("enp175s0f1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("0000:af:00.1"))
+		})
+		It("Not existing PF", func() {
+			_, err := GetPfPciAddress("enp175s0f2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetPhysSwitchID function", func() {
+		It("Switchdev port", func() {
+			result, err := GetPhysSwitchID("pf0vf0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("a8e4570003f65a08"))
+		})
+		It("Not a switchdev port", func() {
+			_, err := GetPhysSwitchID("enp175s6")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking parseVxlanVniFilter function", func() {
+		// vxlanLinkMsg returns RTM_NEWLINK message with VXLAN info data attributes
+		vxlanLinkMsg := func(data ...*nl.RtAttr) []byte {
+			linkInfo := nl.NewRtAttr(unix.IFLA_LINKINFO, nil)
+			linkInfo.AddRtAttr(unix.IFLA_INFO_KIND, nl.NonZeroTerminated("vxlan"))
+			infoData := linkInfo.AddRtAttr(unix.IFLA_INFO_DATA, nil)
+			for _, d := range data {
+				infoData.AddChild(d)
+			}
+			msg := nl.NewIfInfomsg(unix.AF_UNSPEC).Serialize()
+			msg = append(msg, nl.NewRtAttr(unix.IFLA_IFNAME, nl.ZeroTerminated("vxlan0")).Serialize()...)
+			return append(msg, linkInfo.Serialize()...)
+		}
+		It("VNI filtering is enabled", func() {
+			enabled, err := parseVxlanVniFilter(vxlanLinkMsg(
+				nl.NewRtAttr(unix.IFLA_VXLAN_COLLECT_METADATA, nl.Uint8Attr(1)),
+				nl.NewRtAttr(unix.IFLA_VXLAN_VNIFILTER, nl.Uint8Attr(1))))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enabled).To(BeTrue())
+		})
+		It("VNI filtering is disabled", func() {
+			enabled, err := parseVxlanVniFilter(vxlanLinkMsg(
+				nl.NewRtAttr(unix.IFLA_VXLAN_COLLECT_METADATA, nl.Uint8Attr(1)),
+				nl.NewRtAttr(unix.IFLA_VXLAN_VNIFILTER, nl.Uint8Attr(0))))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enabled).To(BeFalse())
+		})
+		It("Kernel without VNI filtering support", func() {
+			enabled, err := parseVxlanVniFilter(vxlanLinkMsg(
+				nl.NewRtAttr(unix.IFLA_VXLAN_COLLECT_METADATA, nl.Uint8Attr(1))))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enabled).To(BeFalse())
+		})
+	})
+	Context("Checking parseVxlanVniList function", func() {
+		// vniListMsg returns RTM_NEWTUNNEL message of the device with VNI filter entries
+		vniListMsg := func(ifindex uint32, entries ...[]uint32) []byte {
+			msg := (&tunnelMsg{family: unix.AF_BRIDGE, ifindex: ifindex}).Serialize()
+			for _, e := range entries {
+				entry := nl.NewRtAttr(unix.NLA_F_NESTED|vxlanVnifilterEntry, nil)
+				entry.AddRtAttr(vxlanVnifilterEntryStart, nl.Uint32Attr(e[0]))
+				if len(e) > 1 {
+					entry.AddRtAttr(vxlanVnifilterEntryEnd, nl.Uint32Attr(e[1]))
+				}
+				msg = append(msg, entry.Serialize()...)
+			}
+			return msg
+		}
+		It("VNI filter entries and ranges", func() {
+			vnis, err := parseVxlanVniList(vniListMsg(7, []uint32{5000}, []uint32{6000, 6002}), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vnis).To(Equal([]uint32{5000, 6000, 6001, 6002}))
+		})
+		It("Message of another device", func() {
+			vnis, err := parseVxlanVniList(vniListMsg(8, []uint32{5000}), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vnis).To(BeEmpty())
+		})
+		It("Short message", func() {
+			_, err := parseVxlanVniList([]byte{unix.AF_BRIDGE}, 7)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Context("Checking GetParentBridgeForLink function", func() {
 		var (
 			nLinkMock *mocks.Netlink
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// VdpaBus is sysfs vdpa device directory
+	VdpaBus = "/sys/bus/vdpa/devices"
+	// vhostVdpaDevDir is a directory of vhost-vdpa character devices
+	vhostVdpaDevDir = "/dev"
+)
+
+const (
+	// VdpaDriverVhost is a vDPA bus driver which exposes the device to userspace as /dev/vhost-vdpa-N
+	VdpaDriverVhost = "vhost_vdpa"
+	// VdpaDriverVirtio is a vDPA bus driver which exposes the device as virtio netdevice
+	VdpaDriverVirtio = "virtio_vdpa"
+)
+
+// VdpaDevice represents vDPA device created on top of a VF
+type VdpaDevice struct {
+	// name of the vDPA device, e.g. vdpa0
+	Name string
+	// vDPA bus driver of the device
+	Driver string
+	// path to vhost-vdpa character device, set for vhost_vdpa driver
+	Path string
+	// name of virtio netdevice, set for virtio_vdpa driver
+	NetDev string
+}
+
+// GetVdpaDevice returns vDPA device created on top of the VF with provided PCI address,
+// nil is returned if the VF has no vDPA device
+func GetVdpaDevice(pciAddr string) (*VdpaDevice, error) {
+	entries, err := os.ReadDir(VdpaBus)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// vdpa bus is not available
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read vdpa devices: %v", err)
+	}
+	for _, e := range entries {
+		devPath, err := filepath.EvalSymlinks(filepath.Join(VdpaBus, e.Name()))
+		if err != nil {
+			continue
+		}
+		// vdpa device is a child of the VF PCI device, e.g.
+		// /sys/devices/pci0000:00/0000:00:02.0/0000:05:00.2/mlx5_core.vnet.2/vdpa2
+		if !strings.Contains(devPath, string(filepath.Separator)+pciAddr+string(filepath.Separator)) {
+			continue
+		}
+		return getVdpaDeviceInfo(e.Name())
+	}
+	return nil, nil
+}
+
+func getVdpaDeviceInfo(name string) (*VdpaDevice, error) {
+	devDir := filepath.Join(VdpaBus, name)
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(devDir, "driver"))
+	if err != nil {
+		return nil, fmt.Errorf("vDPA device %s is not bound to a driver: %v", name, err)
+	}
+	dev := &VdpaDevice{Name: name, Driver: filepath.Base(driverPath)}
+
+	switch dev.Driver {
+	case VdpaDriverVhost:
+		matches, _ := filepath.Glob(filepath.Join(devDir, "vhost-vdpa-*"))
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("failed to find vhost-vdpa device for vDPA device %s", name)
+		}
+		dev.Path = filepath.Join(vhostVdpaDevDir, filepath.Base(matches[0]))
+	case VdpaDriverVirtio:
+		matches, _ := filepath.Glob(filepath.Join(devDir, "virtio*", "net", "*"))
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("failed to find virtio netdevice for vDPA device %s", name)
+		}
+		dev.NetDev = filepath.Base(matches[0])
+	default:
+		return nil, fmt.Errorf("vDPA device %s has unsupported driver %s, supported drivers: %s, %s",
+			name, dev.Driver, VdpaDriverVhost, VdpaDriverVirtio)
+	}
+	return dev, nil
+}